@@ -0,0 +1,332 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/storage"
+	"greenlight.wook.net/internal/validator"
+)
+
+// 내보내기 파일과 다운로드 링크의 유효 기간입니다.
+const exportTTL = 24 * time.Hour
+
+// deleteCurrentUserHandler()는 비밀번호를 다시 확인한 뒤 현재 사용자의 계정을 삭제합니다.
+// 토큰, 권한, API 키, TOTP 설정과 복구 코드는 데이터베이스에서 함께 삭제되고, 사용자가 등록한 movie와
+// 작성한 movie 버전은 작성자 정보만 지워집니다.
+func (app *application) deleteCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// API 키 생성과 마찬가지로 유출된 키 하나로 계정을 삭제할 수 없도록 API 키 인증은 허용하지 않습니다.
+	if app.contextGetApiKey(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Password != "", "password", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// 사용자 레코드가 삭제되면 내보내기 토큰도 사라져 파일 키를 알 수 없으므로 먼저 파일을 지웁니다.
+	err = app.deleteExports(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.Delete(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LoginFailures.Reset(r.Context(), data.LoginScopeAccount, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "your account has been deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// requestExportHandler()는 현재 사용자의 개인 데이터를 담은 ZIP 파일을 백그라운드에서 만들고
+// 완료되면 다운로드 링크를 이메일로 보냅니다. 이전에 만든 내보내기 파일과 링크는 무효화됩니다.
+func (app *application) requestExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	app.background("data export", func(ctx context.Context) {
+		err := app.exportUserData(ctx, user)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"user_id": fmt.Sprint(user.ID)})
+		}
+	})
+
+	env := envelope{"message": "your data export is being prepared and a download link will be sent to your email address"}
+
+	err := app.writeJSON(w, r, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// downloadExportHandler()는 이메일 링크의 토큰으로 내보내기 파일을 제공합니다.
+// 브라우저에서 링크를 바로 열 수 있도록 Authorization 헤더 대신 경로의 토큰으로 인증합니다.
+func (app *application) downloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	plaintext := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, plaintext); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeExport, plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	rc, err := app.storage.Get(r.Context(), exportKey(user.ID, hash[:]))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="greenlight-export.zip"`)
+
+	_, err = io.Copy(w, rc)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// exportKey()는 내보내기 토큰 해시로부터 저장소 키를 만듭니다. 토큰 일반 텍스트는 저장하지 않으므로
+// 키를 알더라도 다운로드 링크를 만들 수 없습니다.
+func exportKey(userID int64, tokenHash []byte) string {
+	return fmt.Sprintf("exports/%d/%x.zip", userID, tokenHash)
+}
+
+// deleteExports()는 사용자의 기존 내보내기 파일과 토큰을 삭제합니다.
+func (app *application) deleteExports(ctx context.Context, userID int64) error {
+	tokens, err := app.models.Tokens.GetAllForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.Scope != data.ScopeExport {
+			continue
+		}
+
+		err = app.storage.Delete(ctx, exportKey(userID, token.Hash))
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.models.Tokens.DeleteAllForUser(ctx, data.ScopeExport, userID)
+}
+
+// exportUserData()는 사용자 데이터를 ZIP 파일로 묶어 저장소에 올리고 다운로드 링크를 이메일로 보냅니다.
+func (app *application) exportUserData(ctx context.Context, user *data.User) error {
+	err := app.deleteExports(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := app.models.Tokens.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	type session struct {
+		Scope  string    `json:"scope"`
+		Expiry time.Time `json:"expiry"`
+	}
+
+	sessions := make([]session, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = session{Scope: token.Scope, Expiry: token.Expiry}
+	}
+
+	apiKeys, err := app.models.ApiKeys.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	movies, err := app.models.Movies.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	app.setPosterURLs(movies...)
+
+	versions, err := app.models.MovieVersions.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	mfa, err := app.exportMFA(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	archive, err := buildExportArchive([]exportFile{
+		{"profile.json", user},
+		{"permissions.json", permissions},
+		{"sessions.json", sessions},
+		{"api_keys.json", apiKeys},
+		{"movies.json", movies},
+		{"movie_versions.json", versions},
+		{"mfa.json", mfa},
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(ctx, user.ID, exportTTL, data.ScopeExport)
+	if err != nil {
+		return err
+	}
+
+	err = app.storage.Put(ctx, exportKey(user.ID, token.Hash), bytes.NewReader(archive), "application/zip")
+	if err != nil {
+		return err
+	}
+
+	return app.mailer.Send(user.Email, "data_export.tmpl", map[string]any{
+		"name":   user.Name,
+		"link":   strings.TrimSuffix(app.config.baseURL, "/") + "/v1/exports/" + token.Plaintext,
+		"expiry": token.Expiry.UTC().Format(time.RFC1123),
+	})
+}
+
+// mfaExport는 내보내기 파일에 포함되는 TOTP 등록 상태입니다. TOTP 비밀 키와 복구 코드는 개인 데이터가
+// 아니라 로그인에 쓰이는 자격 증명이므로, 파일이 유출되어도 두 번째 인증 요소가 노출되지 않도록 제외합니다.
+type mfaExport struct {
+	Enrolled               bool       `json:"enrolled"`
+	Enabled                bool       `json:"enabled"`
+	EnrolledAt             *time.Time `json:"enrolled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+func (app *application) exportMFA(ctx context.Context, userID int64) (*mfaExport, error) {
+	mfa, err := app.models.MFA.Get(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return &mfaExport{}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	remaining, err := app.models.MFA.RecoveryCodesRemaining(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mfaExport{
+		Enrolled:               true,
+		Enabled:                mfa.Enabled,
+		EnrolledAt:             &mfa.CreatedAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// exportFile은 내보내기 ZIP 파일에 JSON으로 기록할 파일 하나입니다.
+type exportFile struct {
+	name  string
+	value any
+}
+
+// buildExportArchive()는 주어진 값들을 들여쓰기된 JSON 파일로 기록한 ZIP 파일을 만듭니다.
+func buildExportArchive(files []exportFile) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		js, err := json.MarshalIndent(file.value, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Write(append(js, '\n'))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/validator"
+)
+
+func (app *application) createApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		Expiry      *time.Time `json:"expiry"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// API 키로 다른 API 키를 만들 수 있으면 유출된 키 하나로 폐기를 우회할 수 있으므로
+	// 키 생성은 비밀번호로 발급받은 인증 토큰으로만 허용합니다.
+	if app.contextGetApiKey(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	key := &data.ApiKey{
+		UserID:      user.ID,
+		Name:        input.Name,
+		Permissions: input.Permissions,
+		Expiry:      input.Expiry,
+	}
+
+	v := validator.New()
+	if data.ValidateApiKey(v, key); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// 키에는 소유자가 현재 가진 권한의 부분 집합만 부여할 수 있습니다.
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, code := range key.Permissions {
+		if !permissions.Include(code) {
+			v.AddError("permissions", "must only contain permissions you hold")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	key, err = app.models.ApiKeys.New(r.Context(), key.UserID, key.Name, key.Permissions, key.Expiry)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// 일반 텍스트 키는 이 응답에서 한 번만 노출됩니다.
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"api_key": key}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	keys, err := app.models.ApiKeys.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"api_keys": keys}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.ApiKeys.Delete(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "api key successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
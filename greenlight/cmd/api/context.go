@@ -14,6 +14,9 @@ type contextKey string
 // 이 상수를 요청 컨텍스트에서 사용자 정보를 가져오고 설정하는 데 키로 사용할 것입니다.
 const userContextKey = contextKey("user")
 
+// 요청이 API 키로 인증된 경우 해당 키를 저장하는 데 사용하는 키입니다.
+const apiKeyContextKey = contextKey("apiKey")
+
 // contextSetUser() 메서드는 제공된 User 구조체를 컨텍스트에 추가한 새 요청 사본을 반환합니다.
 // 여기서 우리는 userContextKey 상수를 키로 사용합니다.
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
@@ -33,3 +36,16 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+// contextSetApiKey()는 요청을 인증한 API 키를 컨텍스트에 추가한 새 요청 사본을 반환합니다.
+func (app *application) contextSetApiKey(r *http.Request, key *data.ApiKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetApiKey()는 요청을 인증한 API 키를 반환합니다. 사용자 정보와 달리 API 키는
+// 토큰으로 인증된 요청이나 익명 요청에는 없는 것이 정상이므로 이 경우 nil을 반환합니다.
+func (app *application) contextGetApiKey(r *http.Request) *data.ApiKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*data.ApiKey)
+	return key
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"greenlight.wook.net/internal/i18n"
+	"greenlight.wook.net/internal/validator"
+)
+
+// originPattern은 신뢰할 수 있는 출처 하나를 나타냅니다. wildcard가 true이면
+// "https://*.example.com"처럼 host의 모든 하위 도메인과 일치하며 example.com 자체와는 일치하지 않습니다.
+type originPattern struct {
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+	raw      string
+}
+
+// parseOriginPattern()은 "scheme://host[:port]" 형식의 출처 또는 가장 왼쪽 레이블이 "*"인 패턴을 해석합니다.
+func parseOriginPattern(s string) (originPattern, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return originPattern{}, i18n.Errorf("invalid origin %q", s)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return originPattern{}, i18n.Errorf("origin %q must use http or https", s)
+	}
+
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return originPattern{}, i18n.Errorf("origin %q must not contain a path, query or credentials", s)
+	}
+
+	p := originPattern{
+		scheme: strings.ToLower(u.Scheme),
+		host:   strings.ToLower(u.Hostname()),
+		port:   u.Port(),
+		raw:    s,
+	}
+
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.wildcard = true
+		p.host = rest
+	}
+
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, i18n.Errorf("origin %q may only use a wildcard as its leftmost label", s)
+	}
+
+	return p, nil
+}
+
+func (p originPattern) match(origin originPattern) bool {
+	if p.scheme != origin.scheme || p.port != origin.port || origin.wildcard {
+		return false
+	}
+
+	if p.wildcard {
+		return strings.HasSuffix(origin.host, "."+p.host)
+	}
+
+	return p.host == origin.host
+}
+
+// originList는 신뢰할 수 있는 출처 목록입니다. 관리자 엔드포인트에서 서버를 재시작하지 않고
+// 목록을 교체할 수 있으므로 요청 처리와 동시에 안전하게 읽고 쓸 수 있도록 뮤텍스로 보호합니다.
+type originList struct {
+	mu       sync.RWMutex
+	patterns []originPattern
+}
+
+func newOriginList(patterns []string) (*originList, error) {
+	l := &originList{}
+
+	err := l.Set(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Set()은 모든 패턴이 올바른 경우에만 목록 전체를 교체합니다.
+func (l *originList) Set(patterns []string) error {
+	parsed := make([]originPattern, 0, len(patterns))
+
+	for _, s := range patterns {
+		p, err := parseOriginPattern(s)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, p)
+	}
+
+	l.mu.Lock()
+	l.patterns = parsed
+	l.mu.Unlock()
+
+	return nil
+}
+
+func (l *originList) List() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	list := make([]string, len(l.patterns))
+	for i, p := range l.patterns {
+		list[i] = p.raw
+	}
+
+	return list
+}
+
+func (l *originList) Allowed(origin string) bool {
+	o, err := parseOriginPattern(origin)
+	if err != nil {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, p := range l.patterns {
+		if p.match(o) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsPolicy는 경로 하나에 적용할 CORS 규칙입니다. origins가 nil이면 모든 출처를 "*"로 허용하며,
+// 이 경우 브라우저가 허용하지 않으므로 credentials는 항상 false여야 합니다.
+type corsPolicy struct {
+	origins        *originList
+	allowedMethods []string
+	allowedHeaders []string
+	exposedHeaders []string
+	maxAge         time.Duration
+	credentials    bool
+}
+
+// corsRoute는 prefix로 시작하는 경로에 기본 정책 대신 적용할 정책입니다.
+type corsRoute struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// corsPolicies()는 경로별 정책 목록과 기본 정책을 반환합니다. 인증이 필요 없는 공개 경로는
+// 모든 출처에 열어 두고, 나머지는 신뢰할 수 있는 출처 목록을 기준으로 판단합니다.
+func (app *application) corsPolicies() ([]corsRoute, *corsPolicy) {
+	public := &corsPolicy{
+		allowedMethods: []string{http.MethodGet},
+		exposedHeaders: app.config.cors.exposedHeaders,
+		maxAge:         app.config.cors.maxAge,
+	}
+
+	routes := []corsRoute{
+		{"/v1/healthcheck", public},
+		{"/v1/healthz/", public},
+	}
+
+	if app.config.storage.backend == "local" && strings.HasPrefix(app.config.storage.localURL, "/") {
+		routes = append(routes, corsRoute{app.config.storage.localURL + "/posters/", public})
+	}
+
+	authenticated := &corsPolicy{
+		origins:        app.corsOrigins,
+		allowedMethods: []string{http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		allowedHeaders: []string{"Authorization", "Content-Type"},
+		exposedHeaders: app.config.cors.exposedHeaders,
+		maxAge:         app.config.cors.maxAge,
+		credentials:    app.config.cors.allowCredentials,
+	}
+
+	return routes, authenticated
+}
+
+// corsPolicyFor()는 경로와 일치하는 정책 중 가장 긴 접두사를 가진 정책을 선택합니다.
+func corsPolicyFor(routes []corsRoute, fallback *corsPolicy, path string) *corsPolicy {
+	policy, longest := fallback, -1
+
+	for _, route := range routes {
+		if strings.HasPrefix(path, route.prefix) && len(route.prefix) > longest {
+			policy, longest = route.policy, len(route.prefix)
+		}
+	}
+
+	return policy
+}
+
+// apply()는 요청의 출처가 허용되면 CORS 헤더를 설정하고, 사전 점검 요청을 처리했다면 true를 반환합니다.
+func (p *corsPolicy) apply(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	if p.origins == nil {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		if !p.origins.Allowed(origin) {
+			return false
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if p.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	// 요청에 HTTP 메서드 OPTIONS가 있고 "Access-Control-Request-Method" 헤더가
+	// 포함되어 있는지 확인합니다. 포함되어 있으면 사전 점검 요청으로 처리합니다.
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.allowedMethods, ", "))
+		if len(p.allowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.allowedHeaders, ", "))
+		}
+		if p.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.maxAge.Seconds())))
+		}
+		//  200 OK 상태와 함께 헤더를 작성하고 추가 작업 없이 미들웨어에서 반환합니다.
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
+
+	if len(p.exposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.exposedHeaders, ", "))
+	}
+
+	return false
+}
+
+func (app *application) showCORSOriginsHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"trusted_origins": app.corsOrigins.List()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateCORSOriginsHandler()는 신뢰할 수 있는 출처 목록을 요청 본문의 목록으로 교체합니다.
+// 변경 사항은 메모리에만 반영되므로 재시작 후에도 유지하려면 -cors-trusted-origins 플래그도 수정해야 합니다.
+func (app *application) updateCORSOriginsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TrustedOrigins []string `json:"trusted_origins"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.TrustedOrigins != nil, "trusted_origins", "must be provided")
+	v.Check(validator.Unique(input.TrustedOrigins), "trusted_origins", "must not contain duplicate values")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.corsOrigins.Set(input.TrustedOrigins)
+	if err != nil {
+		app.failedValidationErrorsResponse(w, r, map[string]error{"trusted_origins": err})
+		return
+	}
+
+	app.logger.PrintInfo("cors trusted origins updated", map[string]string{
+		"trusted_origins": strings.Join(input.TrustedOrigins, " "),
+	})
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"trusted_origins": app.corsOrigins.List()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSTestApplication(t *testing.T, origins ...string) *application {
+	t.Helper()
+
+	app := newTestApplication("production")
+	app.config.cors.maxAge = 10 * time.Minute
+	app.config.cors.exposedHeaders = []string{"Retry-After"}
+
+	list, err := newOriginList(origins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.corsOrigins = list
+
+	return app
+}
+
+func TestOriginListAllowed(t *testing.T) {
+	list, err := newOriginList([]string{"http://localhost:9000", "https://*.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:9000", true},
+		{"http://LOCALHOST:9000", true},
+		{"http://localhost:9001", false},
+		{"https://localhost:9000", false},
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"https://evilexample.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://*.example.com", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		if got := list.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %t; want %t", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestOriginListSetRejectsInvalid(t *testing.T) {
+	list, err := newOriginList([]string{"http://localhost:9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"localhost:9000", "ftp://example.com", "https://example.com/path", "https://api.*.example.com", "https://*"} {
+		if err := list.Set([]string{"https://ok.example.com", pattern}); err == nil {
+			t.Errorf("Set(%q) error = nil; want error", pattern)
+		}
+	}
+
+	// 실패한 Set() 호출은 기존 목록을 바꾸지 않아야 합니다.
+	if got := list.List(); len(got) != 1 || got[0] != "http://localhost:9000" {
+		t.Errorf("List() = %q; want [http://localhost:9000]", got)
+	}
+}
+
+// 다른 출처의 페이지에서 fetch()로 GET /v1/healthcheck를 호출합니다.
+// 공개 경로이므로 신뢰할 수 있는 출처가 아니어도 허용됩니다.
+func TestCORSSimpleRequest(t *testing.T) {
+	app := newCORSTestApplication(t, "http://localhost:9000")
+	handler := app.enableCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		allowOrigin string
+	}{
+		{"public route", "/v1/healthcheck", "http://anywhere.test", "*"},
+		{"trusted origin", "/v1/movies", "http://localhost:9000", "http://localhost:9000"},
+		{"untrusted origin", "/v1/movies", "http://anywhere.test", ""},
+		{"no origin", "/v1/movies", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusTeapot {
+				t.Errorf("status = %d; want the next handler to run", rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q; want %q", got, tt.allowOrigin)
+			}
+
+			wantExpose := ""
+			if tt.allowOrigin != "" {
+				wantExpose = "Retry-After"
+			}
+			if got := rr.Header().Get("Access-Control-Expose-Headers"); got != wantExpose {
+				t.Errorf("Access-Control-Expose-Headers = %q; want %q", got, wantExpose)
+			}
+			if got := rr.Header().Values("Vary"); len(got) != 2 {
+				t.Errorf("Vary = %q; want Origin and Access-Control-Request-Method", got)
+			}
+		})
+	}
+}
+
+// 다른 출처의 페이지가 JSON 본문으로 POST /v1/tokens/authentication을
+// 호출하기 전에 브라우저가 보내는 사전 점검 요청을 확인합니다.
+func TestCORSPreflightRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		origin      string
+		credentials bool
+		preflight   bool
+	}{
+		{"trusted origin", "http://localhost:9000", false, true},
+		{"wildcard origin with credentials", "https://app.example.com", true, true},
+		{"untrusted origin", "http://localhost:9001", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newCORSTestApplication(t, "http://localhost:9000", "https://*.example.com")
+			app.config.cors.allowCredentials = tt.credentials
+
+			handler := app.enableCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}))
+
+			r := httptest.NewRequest(http.MethodOptions, "/v1/tokens/authentication", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			r.Header.Set("Access-Control-Request-Headers", "content-type")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if !tt.preflight {
+				if rr.Code != http.StatusTeapot || rr.Header().Get("Access-Control-Allow-Origin") != "" {
+					t.Errorf("untrusted preflight was answered: status %d, headers %v", rr.Code, rr.Header())
+				}
+				return
+			}
+
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      tt.origin,
+				"Access-Control-Allow-Methods":     "OPTIONS, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Allow-Credentials": "",
+			}
+			if tt.credentials {
+				want["Access-Control-Allow-Credentials"] = "true"
+			}
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("status = %d; want %d", rr.Code, http.StatusOK)
+			}
+			for header, value := range want {
+				if got := rr.Header().Get(header); got != value {
+					t.Errorf("%s = %q; want %q", header, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSReload(t *testing.T) {
+	app := newCORSTestApplication(t, "http://localhost:9000")
+	handler := app.enableCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	allowed := func(origin string) bool {
+		r := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+		r.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+
+	if !allowed("http://localhost:9000") || allowed("https://new.example.com") {
+		t.Fatal("unexpected initial policy")
+	}
+
+	err := app.corsOrigins.Set([]string{"https://new.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allowed("http://localhost:9000") || !allowed("https://new.example.com") {
+		t.Error("middleware did not pick up the reloaded origin list")
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"greenlight.wook.net/internal/i18n"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -14,15 +18,51 @@ func (app *application) logError(r *http.Request, err error) {
 	})
 }
 
+// language()는 요청의 Accept-Language 헤더를 기준으로 응답 메시지에 사용할 언어를 선택합니다.
+func (app *application) language(r *http.Request) string {
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// translate()는 오류 메시지를 요청 언어로 번역합니다. 문자열은 그대로 카탈로그 키로 사용하고,
+// 유효성 검사 오류 맵은 각 값을, error와 error 맵의 값은 i18n.TranslateError()로 번역합니다.
+func (app *application) translate(r *http.Request, message any) any {
+	lang := app.language(r)
+
+	switch m := message.(type) {
+	case string:
+		return i18n.Translate(lang, m)
+	case map[string]string:
+		translated := make(map[string]string, len(m))
+		for key, value := range m {
+			translated[key] = i18n.Translate(lang, value)
+		}
+		return translated
+	case map[string]error:
+		translated := make(map[string]string, len(m))
+		for key, err := range m {
+			translated[key] = i18n.TranslateError(lang, err)
+		}
+		return translated
+	case error:
+		return i18n.TranslateError(lang, m)
+	default:
+		return message
+	}
+}
+
 // errorResponse() 메서드는 주어진 상태 코드와 함께 JSON 형식의 오류 메시지를 클라이언트에 전송하는
 // generic 헬퍼입니다. 메시지 매개변수에 문자열 유형이
 // 아닌 임의의 유형을 사용하는 이유는 응답에 포함할 수 있는 값을 보다 유연하게 설정할 수 있기 때문입니다.
-func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := envelope{"error": message}
+// code는 클라이언트가 번역된 메시지 대신 비교할 수 있는 고정된 오류 식별자입니다.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code string, message any) {
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", app.language(r))
+
+	env := envelope{"error": app.translate(r, message), "code": code}
 	// writeJSON() 헬퍼를 사용하여 응답을 작성합니다. 이 과정에서 오류가 반환되면
 	// 이를 기록하고 클라이언트에 500 내부 서버 오류 상태 코드가 포함된
 	// 빈 응답을 보내는 것으로 되돌아갑니다.
-	err := app.writeJSON(w, status, env, nil)
+	err := app.writeJSON(w, r, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
@@ -37,64 +77,83 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logError(r, err)
 
-	message := "서버에 문제가 발생하여 요청을 처리할 수 없습니다."
-	app.errorResponse(w, r, http.StatusInternalServerError, message)
+	message := "the server encountered a problem and could not process your request"
+	app.errorResponse(w, r, http.StatusInternalServerError, "server_error", message)
 }
 
 // notFoundResponse() 메서드는 404 찾을 수 없음 상태 코드와
 // JSON 응답을 클라이언트에 전송하는 데 사용됩니다.
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
-	message := "요청한 리소스를 찾을 수 없습니다."
-	app.errorResponse(w, r, http.StatusNotFound, message)
+	message := "the requested resource could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, "not_found", message)
 }
 
 // NotAllowedResponse() 메서드는 405 메서드 허용되지 않음 상태 코드와 JSON 응답을 클라이언트에 전송하는 데 사용됩니다.
 func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
-	message := fmt.Sprintf("이 리소스에는 %s 메서드가 지원되지 않습니다.", r.Method)
-	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
+	message := i18n.Errorf("the %s method is not supported for this resource", r.Method)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message)
 }
 
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+	app.errorResponse(w, r, http.StatusBadRequest, "bad_request", err)
 }
 
 // 여기서 오류 매개변수는 유효성 검사기 유형에 포함된 오류 맵과 정확히 동일한 map[string]string 유형을 가집니다.
 func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, "failed_validation", errors)
+}
+
+// failedValidationErrorsResponse()는 failedValidationResponse()와 같지만 i18n.Errorf()로 만든 오류처럼
+// 인수가 있는 메시지를 받아, 다른 메시지와 같이 응답을 쓸 때 한 번만 번역합니다.
+func (app *application) failedValidationErrorsResponse(w http.ResponseWriter, r *http.Request, errors map[string]error) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, "failed_validation", errors)
 }
 
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
-	message := "충돌로 인해 레코드를 업데이트할 수 없습니다. 다시 시도하세요."
-	app.errorResponse(w, r, http.StatusConflict, message)
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, "edit_conflict", message)
 }
 
 func (app *application) rateLimitExccededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
-	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+	app.errorResponse(w, r, http.StatusTooManyRequests, "rate_limited", message)
+}
+
+// Retry-After 헤더에는 남은 대기 시간을 초 단위로 올림하여 설정합니다.
+func (app *application) tooManyLoginAttemptsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, "too_many_login_attempts", message)
+}
+
+func (app *application) accountLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	message := "this account has been temporarily locked because of too many failed login attempts"
+	app.errorResponse(w, r, http.StatusTooManyRequests, "account_locked", message)
 }
 
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_credentials", message)
 }
 
 func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 	message := "invalid or missing authentication token"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_authentication_token", message)
 }
 
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "authentication_required", message)
 }
 
 func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account must be activated to access this resource"
-	app.errorResponse(w, r, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "inactive_account", message)
 }
 
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
-	app.errorResponse(w, r, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "not_permitted", message)
 }
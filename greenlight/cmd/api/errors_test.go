@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"greenlight.wook.net/internal/i18n"
+)
+
+func TestErrorResponseLocalization(t *testing.T) {
+	app := newTestApplication("production")
+
+	tests := []struct {
+		name     string
+		language string
+		respond  func(w http.ResponseWriter, r *http.Request)
+		status   int
+		code     string
+		message  any
+	}{
+		{
+			name:    "default language",
+			respond: app.editConflictResponse,
+			status:  http.StatusConflict,
+			code:    "edit_conflict",
+			message: "unable to update the record due to an edit conflict, please try again",
+		},
+		{
+			name:     "korean",
+			language: "ko-KR,ko;q=0.9,en;q=0.8",
+			respond:  app.notFoundResponse,
+			status:   http.StatusNotFound,
+			code:     "not_found",
+			message:  "요청한 리소스를 찾을 수 없습니다.",
+		},
+		{
+			name:     "formatted message",
+			language: "ko",
+			respond:  app.methodNotAllowedResponse,
+			status:   http.StatusMethodNotAllowed,
+			code:     "method_not_allowed",
+			message:  "이 리소스에는 GET 메서드가 지원되지 않습니다.",
+		},
+		{
+			name:     "validation errors",
+			language: "ko",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				app.failedValidationResponse(w, r, map[string]string{"title": "must be provided", "page": "must be greater than zero"})
+			},
+			status:  http.StatusUnprocessableEntity,
+			code:    "failed_validation",
+			message: map[string]any{"title": "반드시 입력해야 합니다", "page": "0 보다 커야만 합니다"},
+		},
+		{
+			name:     "formatted validation errors",
+			language: "ko",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				app.failedValidationErrorsResponse(w, r, map[string]error{"poster": i18n.Errorf("must not be larger than %dx%d pixels", 8000, 8000)})
+			},
+			status:  http.StatusUnprocessableEntity,
+			code:    "failed_validation",
+			message: map[string]any{"poster": "8000x8000 픽셀보다 클 수 없습니다"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.language != "" {
+				r.Header.Set("Accept-Language", tt.language)
+			}
+
+			rr := httptest.NewRecorder()
+			tt.respond(rr, r)
+
+			if rr.Code != tt.status {
+				t.Errorf("status = %d; want %d", rr.Code, tt.status)
+			}
+
+			var body struct {
+				Code  string `json:"code"`
+				Error any    `json:"error"`
+			}
+			err := json.NewDecoder(rr.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if body.Code != tt.code {
+				t.Errorf("code = %q; want %q", body.Code, tt.code)
+			}
+
+			got, _ := json.Marshal(body.Error)
+			want, _ := json.Marshal(tt.message)
+			if string(got) != string(want) {
+				t.Errorf("error = %s; want %s", got, want)
+			}
+
+			wantLanguage := "en"
+			if tt.language != "" {
+				wantLanguage = "ko"
+			}
+			if got := rr.Header().Get("Content-Language"); got != wantLanguage {
+				t.Errorf("Content-Language = %q; want %q", got, wantLanguage)
+			}
+		})
+	}
+}
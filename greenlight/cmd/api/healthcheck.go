@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"sync"
+	"time"
 )
 
+// 각 의존성 검사에 허용되는 최대 시간입니다.
+const healthCheckTimeout = 2 * time.Second
+
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := envelope{
@@ -14,9 +21,138 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		},
 	}
 
-	err := app.writeJSON(w, http.StatusOK, data, nil)
+	err := app.writeJSON(w, r, http.StatusOK, data, nil)
 	if err != nil {
 		// 새로운 serverErrorResponse() 헬퍼를 사용합니다.
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// livenessHandler()는 프로세스가 요청을 처리할 수 있는지만 알려주며 의존성은 확인하지 않습니다.
+// 데이터베이스 장애로 인해 오케스트레이터가 컨테이너를 재시작하지 않도록 하기 위함입니다.
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"status": "alive"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// healthCheck는 하나의 의존성 검사 결과입니다. critical이 아닌 검사가 실패해도
+// 전체 준비 상태는 유지되며 결과에만 표시됩니다.
+type healthCheck struct {
+	Status   string         `json:"status"`
+	Critical bool           `json:"critical"`
+	Duration string         `json:"duration"`
+	Error    string         `json:"error,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// readinessHandler()는 데이터베이스, SMTP 서버, 마이그레이션 상태를 동시에 검사하고
+// 하나라도 critical 검사가 실패하거나 서버가 종료 중이면 503 Service Unavailable을 반환합니다.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) (map[string]any, error){
+		"database":   app.checkDatabase,
+		"smtp":       app.checkSMTP,
+		"migrations": app.checkMigrations,
+	}
+	critical := map[string]bool{
+		"database":   true,
+		"smtp":       false,
+		"migrations": true,
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]healthCheck, len(checks))
+	)
+
+	for name, check := range checks {
+		wg.Add(1)
+
+		go func(name string, check func(ctx context.Context) (map[string]any, error)) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check(ctx)
+
+			result := healthCheck{
+				Status:   "up",
+				Critical: critical[name],
+				Duration: time.Since(start).String(),
+				Details:  details,
+			}
+			if err != nil {
+				result.Status = "down"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	status := "ready"
+	code := http.StatusOK
+
+	for _, result := range results {
+		if result.Critical && result.Status != "up" {
+			status = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	// serve()가 종료 절차를 시작했다면 의존성 상태와 관계없이 새 트래픽을 받지 않도록 알립니다.
+	if app.draining.Load() {
+		status = "draining"
+		code = http.StatusServiceUnavailable
+	}
+
+	err := app.writeJSON(w, r, code, envelope{"status": status, "checks": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) checkDatabase(ctx context.Context) (map[string]any, error) {
+	err := app.db.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := app.db.Stats()
+
+	return map[string]any{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+	}, nil
+}
+
+func (app *application) checkSMTP(ctx context.Context) (map[string]any, error) {
+	return nil, app.mailer.Ping(ctx)
+}
+
+func (app *application) checkMigrations(ctx context.Context) (map[string]any, error) {
+	version, dirty, err := app.migrator.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{
+		"version": version,
+		"latest":  app.migrator.Latest(),
+		"dirty":   dirty,
+	}
+
+	if dirty {
+		return details, errors.New("database schema is dirty")
+	}
+
+	return details, nil
+}
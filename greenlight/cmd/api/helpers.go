@@ -1,16 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"greenlight.wook.net/internal/i18n"
 	"greenlight.wook.net/internal/validator"
 )
 
@@ -27,8 +29,33 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	js, err := json.MarshalIndent(data, "", "\t")
+// prettyJSON()은 응답 JSON을 들여쓰기할지 결정합니다. "?pretty" 또는 "?pretty=true"로 켜고
+// "?pretty=false"로 끌 수 있으며, 쿼리 문자열에 없으면 개발 환경에서만 들여쓰기합니다.
+func (app *application) prettyJSON(r *http.Request) bool {
+	values, ok := r.URL.Query()["pretty"]
+	if ok {
+		if values[0] == "" {
+			return true
+		}
+
+		pretty, err := strconv.ParseBool(values[0])
+		if err == nil {
+			return pretty
+		}
+	}
+
+	return app.config.env == "development"
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	var js []byte
+	var err error
+
+	if app.prettyJSON(r) {
+		js, err = json.MarshalIndent(data, "", "\t")
+	} else {
+		js, err = json.Marshal(data)
+	}
 	if err != nil {
 		return err
 	}
@@ -46,6 +73,120 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// writeJSONStream()은 listKey 아래의 목록 항목을 하나씩 인코딩하여 바로 응답에 씁니다.
+// writeJSON()과 달리 전체 응답을 메모리에 만들지 않으므로 큰 목록에서 할당이 줄어듭니다.
+// extra의 나머지 키는 먼저 마샬링하므로 그 과정의 오류는 헤더를 쓰기 전에 반환됩니다.
+// 헤더를 쓴 뒤 발생한 오류는 클라이언트에 다른 상태 코드를 보낼 수 없으므로 기록만 하고 nil을 반환합니다.
+// 출력 형식은 같은 envelope을 writeJSON()으로 쓴 결과와 동일합니다.
+func writeJSONStream[T any](app *application, w http.ResponseWriter, r *http.Request, status int, listKey string, items []T, extra envelope, headers http.Header) error {
+	pretty := app.prettyJSON(r)
+
+	// envelope 맵을 마샬링할 때처럼 키를 정렬된 순서로 씁니다.
+	keys := make([]string, 0, len(extra)+1)
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	keys = append(keys, listKey)
+	sort.Strings(keys)
+
+	var head, tail bytes.Buffer
+	current := &head
+
+	head.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			current.WriteByte(',')
+		}
+		if pretty {
+			current.WriteString("\n\t")
+		}
+
+		name, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		current.Write(name)
+		current.WriteByte(':')
+		if pretty {
+			current.WriteByte(' ')
+		}
+
+		if key == listKey {
+			current = &tail
+			continue
+		}
+
+		var value []byte
+		if pretty {
+			value, err = json.MarshalIndent(extra[key], "\t", "\t")
+		} else {
+			value, err = json.Marshal(extra[key])
+		}
+		if err != nil {
+			return err
+		}
+		current.Write(value)
+	}
+	if pretty {
+		tail.WriteByte('\n')
+	}
+	tail.WriteString("}\n")
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// 하나의 버퍼와 인코더를 재사용하여 항목마다 새 바이트 슬라이스를 할당하지 않도록 합니다.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if pretty {
+		enc.SetIndent("\t\t", "\t")
+	}
+
+	buf.Write(head.Bytes())
+	buf.WriteByte('[')
+
+	for i := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if pretty {
+			buf.WriteString("\n\t\t")
+		}
+
+		err := enc.Encode(items[i])
+		if err != nil {
+			app.logError(r, err)
+			return nil
+		}
+		// Encode()가 추가한 줄바꿈을 제거합니다.
+		buf.Truncate(buf.Len() - 1)
+
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			app.logError(r, err)
+			return nil
+		}
+		buf.Reset()
+	}
+
+	if pretty && len(items) > 0 {
+		buf.WriteString("\n\t")
+	}
+	buf.WriteByte(']')
+	buf.Write(tail.Bytes())
+
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	return nil
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	// 요청 본문의 크기를 1MB로 제한하려면 http.MaxBytesReader()를 사용합니다.
 	maxBytes := 1_048_576
@@ -68,19 +209,19 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 
 		switch {
 		case errors.As(err, &syntaxError):
-			return fmt.Errorf("본문에 잘못된 형식의 JSON이 포함되어 있습니다(%d 문자에서)", syntaxError.Offset)
+			return i18n.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
 
 		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("본문에 잘못된 형식의 JSON이 포함되어 있습니다")
+			return i18n.Errorf("body contains badly-formed JSON")
 
 		case errors.As(err, &unmarhalTypeError):
 			if unmarhalTypeError.Field != "" {
-				return fmt.Errorf("본문에 %q 필드에 대한 잘못된 JSON 유형이 있습니다", unmarhalTypeError.Field)
+				return i18n.Errorf("body contains incorrect JSON type for field %q", unmarhalTypeError.Field)
 			}
-			return fmt.Errorf("본문에 잘못된 JSON 형식이 있습니다(%d 문자에서)", unmarhalTypeError.Offset)
+			return i18n.Errorf("body contains incorrect JSON type (at character %d)", unmarhalTypeError.Offset)
 
 		case errors.Is(err, io.EOF):
-			return errors.New("본문이 비어 있지 않아야 합니다")
+			return i18n.Errorf("body must not be empty")
 
 		// JSON에 대상 대상에 매핑할 수 없는 필드가 포함된 경우 이제 Decode()는
 		// "json: 알 수 없는 필드 "<이름>"" 형식의 오류 메시지를 반환합니다.
@@ -89,12 +230,12 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 		// https://github.com/golang/go/issues/29035 에 미해결 이슈가 있다는 점에 유의하세요.
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("본문에 알 수 없는 키 %s가 포함되어 있습니다", fieldName)
+			return i18n.Errorf("body contains unknown key %s", fieldName)
 		// errors.As() 함수를 사용하여 오류의 유형이 *http.MaxBytesError인지 확인합니다.
 		// 만약 그렇다면 요청 본문이 크기 제한인 1MB를 초과했음을 의미하며
 		// 명확한 오류 메시지를 반환합니다.
 		case errors.As(err, &maxBytesError):
-			return fmt.Errorf("본문은 %d bytes보다 크지 않아야 합니다", maxBytesError.Limit)
+			return i18n.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
 
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
@@ -108,7 +249,7 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	// 자체 사용자 정의 오류 메시지를 반환합니다.
 	err = dec.Decode(&struct{}{})
 	if err != io.EOF {
-		return errors.New("본문은 단일 JSON 값만 포함해야 합니다")
+		return i18n.Errorf("body must only contain a single JSON value")
 	}
 
 	return nil
@@ -152,28 +293,9 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 	i, err := strconv.Atoi(s)
 	if err != nil {
-		v.AddError(key, "must be and integer value")
+		v.AddError(key, "must be an integer value")
 		return defaultValue
 	}
 
 	return i
 }
-
-func (app *application) background(fn func()) {
-	// WaitGroup 카운터를 증가시킵니다.
-	app.wg.Add(1)
-
-	go func() {
-
-		// defer를 사용하면 고루틴이 반환되기 전에 WaitGroup 카운터가 감소합니다.
-		defer app.wg.Done()
-
-		defer func() {
-			if err := recover(); err != nil {
-				app.logger.PrintError(fmt.Errorf("%s", err), nil)
-			}
-		}()
-
-		fn()
-	}()
-}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/jsonlog"
+)
+
+func newTestApplication(env string) *application {
+	return &application{
+		config: config{env: env},
+		logger: jsonlog.New(io.Discard, jsonlog.LevelOff),
+		tasks:  newTaskTracker(),
+		now:    time.Now,
+	}
+}
+
+func testMovies(n int) []*data.Movie {
+	movies := make([]*data.Movie, n)
+	for i := range movies {
+		movies[i] = &data.Movie{
+			ID:        int64(i + 1),
+			CreatedAt: time.Now(),
+			Title:     fmt.Sprintf("Movie <%d>", i+1),
+			Year:      2000 + int32(i%20),
+			Runtime:   data.Runtime(90 + i%60),
+			Genres:    []string{"drama", "comedy", "sci-fi"},
+			Version:   1,
+		}
+	}
+	return movies
+}
+
+func TestWriteJSONStreamMatchesWriteJSON(t *testing.T) {
+	metadata := data.Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 3, TotalRecords: 42}
+
+	tests := []struct {
+		name   string
+		target string
+		movies []*data.Movie
+	}{
+		{"compact", "/v1/movies?pretty=false", testMovies(3)},
+		{"pretty", "/v1/movies?pretty", testMovies(3)},
+		{"compact empty", "/v1/movies?pretty=false", []*data.Movie{}},
+		{"pretty empty", "/v1/movies?pretty=true", []*data.Movie{}},
+	}
+
+	app := newTestApplication("production")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			want := httptest.NewRecorder()
+			err := app.writeJSON(want, r, http.StatusOK, envelope{"movies": tt.movies, "metadata": metadata}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := httptest.NewRecorder()
+			err = writeJSONStream(app, got, r, http.StatusOK, "movies", tt.movies, envelope{"metadata": metadata}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Body.String() != want.Body.String() {
+				t.Errorf("stream output differs\ngot:  %q\nwant: %q", got.Body.String(), want.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrettyJSONDefault(t *testing.T) {
+	tests := []struct {
+		env    string
+		target string
+		want   bool
+	}{
+		{"development", "/", true},
+		{"production", "/", false},
+		{"production", "/?pretty", true},
+		{"development", "/?pretty=false", false},
+		{"production", "/?pretty=nonsense", false},
+	}
+
+	for _, tt := range tests {
+		app := newTestApplication(tt.env)
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+		if got := app.prettyJSON(r); got != tt.want {
+			t.Errorf("prettyJSON(%s, %s) = %t; want %t", tt.env, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0", ""},
+		{"br, *;q=0.1", "gzip"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q; want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	app := newTestApplication("production")
+	movies := testMovies(50)
+
+	handler := app.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			app.writeJSON(w, r, http.StatusOK, envelope{"status": "available"}, nil)
+			return
+		}
+		writeJSONStream(app, w, r, http.StatusOK, "movies", movies, nil, nil)
+	}))
+
+	tests := []struct {
+		path     string
+		encoding string
+		want     string
+		reader   func(io.Reader) (io.Reader, error)
+	}{
+		{"/large", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"/large", "deflate", "deflate", func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+		{"/large", "", "", nil},
+		{"/small", "gzip", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.encoding, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.encoding != "" {
+				r.Header.Set("Accept-Encoding", tt.encoding)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q; want %q", got, tt.want)
+			}
+
+			var body io.Reader = rr.Body
+			if tt.reader != nil {
+				var err error
+				body, err = tt.reader(rr.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasSuffix(string(b), "}\n") {
+				t.Errorf("unexpected body %q", b)
+			}
+		})
+	}
+}
+
+func TestCompressSkipsRangesAndBinary(t *testing.T) {
+	app := newTestApplication("production")
+
+	text := bytes.Repeat([]byte("greenlight "), 500)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 4096)...)
+
+	handler := app.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/range":
+			http.ServeContent(w, r, "notes.txt", time.Time{}, bytes.NewReader(text))
+		case "/poster":
+			// Content-Type을 지정하지 않아도 본문에서 추측한 형식으로 판단해야 합니다.
+			w.Write(png)
+		case "/export":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write(text)
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write(text)
+		}
+	}))
+
+	tests := []struct {
+		path       string
+		rangeHdr   string
+		wantStatus int
+		wantBody   []byte
+		compressed bool
+	}{
+		{"/range", "bytes=100-199", http.StatusPartialContent, text[100:200], false},
+		{"/range", "", http.StatusOK, text, false},
+		{"/poster", "", http.StatusOK, png, false},
+		{"/export", "", http.StatusOK, text, false},
+		{"/text", "", http.StatusOK, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.rangeHdr, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			if tt.rangeHdr != "" {
+				r.Header.Set("Range", tt.rangeHdr)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			encoding := rr.Header().Get("Content-Encoding")
+			if tt.compressed {
+				if encoding != "gzip" {
+					t.Errorf("Content-Encoding = %q; want gzip", encoding)
+				}
+				return
+			}
+
+			if encoding != "" {
+				t.Errorf("Content-Encoding = %q; want none", encoding)
+			}
+			if !bytes.Equal(rr.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body is %d bytes; want the %d original bytes", rr.Body.Len(), len(tt.wantBody))
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	app := newTestApplication("production")
+
+	flushed := make(chan []byte, 1)
+
+	handler := app.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"movies":[`))
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("compressed response writer is not an http.Flusher")
+		}
+		f.Flush()
+
+		rr := w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder)
+		flushed <- append([]byte(nil), rr.Body.Bytes()...)
+
+		w.Write([]byte(`]}`))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	// 본문이 작더라도 Flush()를 호출한 시점에 압축된 앞부분이 클라이언트에 전달되어야 합니다.
+	if !rr.Flushed {
+		t.Error("underlying writer was not flushed")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(<-flushed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix, _ := io.ReadAll(zr)
+	if string(prefix) != `{"movies":[` {
+		t.Errorf("flushed %q; want the first write", prefix)
+	}
+
+	zr, err = gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"movies":[]}` {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func benchmarkList(b *testing.B, target string, stream bool) {
+	app := newTestApplication("production")
+	movies := testMovies(100)
+	metadata := data.Metadata{CurrentPage: 1, PageSize: 100, FirstPage: 1, LastPage: 1, TotalRecords: 100}
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+
+		var err error
+		if stream {
+			err = writeJSONStream(app, w, r, http.StatusOK, "movies", movies, envelope{"metadata": metadata}, nil)
+		} else {
+			err = app.writeJSON(w, r, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteJSONPretty(b *testing.B)  { benchmarkList(b, "/v1/movies?pretty", false) }
+func BenchmarkWriteJSONCompact(b *testing.B) { benchmarkList(b, "/v1/movies", false) }
+func BenchmarkStreamPretty(b *testing.B)     { benchmarkList(b, "/v1/movies?pretty", true) }
+func BenchmarkStreamCompact(b *testing.B)    { benchmarkList(b, "/v1/movies", true) }
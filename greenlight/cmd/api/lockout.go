@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"greenlight.wook.net/internal/data"
+)
+
+// loginBackoff()는 연속된 실패 횟수에 따른 대기 시간을 계산합니다. 처음 몇 번의 실패는
+// 오타일 가능성이 높으므로 지연 없이 허용하고, 그 이후에는 실패할 때마다 대기 시간을 두 배로 늘립니다.
+func (app *application) loginBackoff(failures int) time.Duration {
+	excess := failures - app.config.login.freeAttempts
+	if excess <= 0 {
+		return 0
+	}
+
+	delay := app.config.login.backoffBase
+	for i := 1; i < excess && delay < app.config.login.backoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > app.config.login.backoffMax {
+		delay = app.config.login.backoffMax
+	}
+
+	return delay
+}
+
+// loginSubjects()는 실패 기록을 조회하고 갱신할 계정과 IP 주소 키를 반환합니다.
+func loginSubjects(r *http.Request, email string) (account, ip string, err error) {
+	ip, _, err = net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return strings.ToLower(email), ip, nil
+}
+
+// loginPenalty()는 실패 횟수가 failures가 되었을 때의 대기 시간을 정합니다. 계정의 실패 횟수가
+// 임계값에 도달하면 계정을 잠급니다.
+func (app *application) loginPenalty(scope string, failures int) (time.Duration, bool) {
+	if scope == data.LoginScopeAccount && failures >= app.config.login.maxFailures {
+		return app.config.login.lockoutDuration, true
+	}
+	return app.loginBackoff(failures), false
+}
+
+// beginLoginAttempt()는 자격 증명을 확인하기 전에 이번 시도를 계정과 IP 주소의 실패로 미리 기록합니다.
+// 계정이나 IP 주소 중 하나라도 차단되어 있으면 응답을 보내고 nil을 반환합니다. 검사와 기록이
+// 원자적으로 이루어지므로 동시에 보낸 요청으로 차단 전에 여러 번 비밀번호를 시도할 수 없고,
+// 클라이언트가 응답을 기다리지 않고 연결을 끊어도 실패 기록을 피할 수 없습니다.
+func (app *application) beginLoginAttempt(w http.ResponseWriter, r *http.Request, account, ip string) *data.LoginAttempt {
+	attempt, err := app.models.LoginFailures.Attempt(r.Context(), account, ip, app.config.login.failureWindow, app.loginPenalty)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil
+	}
+
+	if attempt.Blocked != nil {
+		_, retryAfter := attempt.Blocked.Blocked(time.Now())
+		if attempt.Blocked.Locked {
+			app.accountLockedResponse(w, r, retryAfter)
+		} else {
+			app.tooManyLoginAttemptsResponse(w, r, retryAfter)
+		}
+		return nil
+	}
+
+	return attempt
+}
+
+// loginFailed()는 실패로 끝난 시도가 계정을 잠갔다면 이를 기록하고, 실제 사용자가 있다면 알림 이메일을 보냅니다.
+func (app *application) loginFailed(attempt *data.LoginAttempt, user *data.User) {
+	if !attempt.Account.Locked {
+		return
+	}
+
+	app.logger.PrintInfo("account locked", map[string]string{
+		"account":  attempt.Account.Subject,
+		"failures": strconv.Itoa(attempt.Account.Failures),
+	})
+
+	if user == nil {
+		return
+	}
+
+	app.background("account locked email", func(ctx context.Context) {
+		data := map[string]any{
+			"name":        user.Name,
+			"failures":    attempt.Account.Failures,
+			"lockedUntil": attempt.Account.BlockedUntil.UTC().Format(time.RFC1123),
+		}
+
+		err := app.mailer.Send(user.Email, "account_locked.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
+
+// unlockUserHandler()는 관리자가 잠긴 계정의 실패 기록을 지워 즉시 로그인할 수 있게 합니다.
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LoginFailures.Reset(r.Context(), data.LoginScopeAccount, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "account successfully unlocked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
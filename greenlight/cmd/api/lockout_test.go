@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"greenlight.wook.net/internal/data"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	app := newTestApplication("production")
+	app.config.login.freeAttempts = 3
+	app.config.login.backoffBase = time.Second
+	app.config.login.backoffMax = 10 * time.Second
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := app.loginBackoff(tt.failures); got != tt.want {
+			t.Errorf("loginBackoff(%d) = %s; want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// loginDB는 로그인 처리에 필요한 쿼리만 이해하는 가짜 데이터베이스 드라이버입니다.
+// 트랜잭션은 시작부터 커밋이나 롤백까지 mu를 잡고 있어 Postgres의 행 잠금처럼 서로를 기다리게 합니다.
+// 사용자는 ID가 1인 한 명뿐이며, TOTP 설정과 복구 코드, 토큰도 이 사용자의 것만 저장합니다.
+type loginDB struct {
+	mu       sync.Mutex
+	failures map[string]*data.LoginFailure
+	email    string
+	hash     []byte
+	mfa      *data.MFA
+	// recovery는 복구 코드 해시별로 사용 여부를, tokens는 토큰 해시별로 범위를 저장합니다.
+	recovery map[string]bool
+	tokens   map[string]string
+}
+
+func newLoginDB(t *testing.T, email, password string) (*loginDB, *sql.DB) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &loginDB{
+		failures: map[string]*data.LoginFailure{},
+		email:    email,
+		hash:     hash,
+		recovery: map[string]bool{},
+		tokens:   map[string]string{},
+	}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return fake, db
+}
+
+func (db *loginDB) failure(scope, subject string) *data.LoginFailure {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.failures[scope+"/"+subject]
+}
+
+func (db *loginDB) mfaState() *data.MFA {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.mfa == nil {
+		return nil
+	}
+	mfa := *db.mfa
+	return &mfa
+}
+
+func (db *loginDB) tokenCount(scope string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	for _, s := range db.tokens {
+		if s == scope {
+			n++
+		}
+	}
+	return n
+}
+
+func (db *loginDB) Connect(context.Context) (driver.Conn, error) { return &loginConn{db: db}, nil }
+func (db *loginDB) Driver() driver.Driver                        { return nil }
+
+type loginConn struct {
+	db *loginDB
+	// snapshot은 트랜잭션이 시작될 때의 상태로, 롤백하면 이 상태로 되돌립니다.
+	snapshot *loginSnapshot
+}
+
+type loginSnapshot struct {
+	failures map[string]data.LoginFailure
+	recovery map[string]bool
+}
+
+func (c *loginConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *loginConn) Close() error { return nil }
+func (c *loginConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *loginConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+
+	c.snapshot = &loginSnapshot{failures: map[string]data.LoginFailure{}, recovery: map[string]bool{}}
+	for key, f := range c.db.failures {
+		c.snapshot.failures[key] = *f
+	}
+	for hash, used := range c.db.recovery {
+		c.snapshot.recovery[hash] = used
+	}
+	return c, nil
+}
+
+func (c *loginConn) Commit() error {
+	c.snapshot = nil
+	c.db.mu.Unlock()
+	return nil
+}
+
+func (c *loginConn) Rollback() error {
+	c.db.failures = map[string]*data.LoginFailure{}
+	for key, f := range c.snapshot.failures {
+		f := f
+		c.db.failures[key] = &f
+	}
+	c.db.recovery = c.snapshot.recovery
+	c.snapshot = nil
+	c.db.mu.Unlock()
+	return nil
+}
+
+// lock()은 트랜잭션 밖에서 실행되는 구문도 진행 중인 트랜잭션을 기다리게 합니다.
+func (c *loginConn) lock() func() {
+	if c.snapshot != nil {
+		return func() {}
+	}
+	c.db.mu.Lock()
+	return c.db.mu.Unlock
+}
+
+func (c *loginConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	defer c.lock()()
+
+	query = strings.Join(strings.Fields(query), " ")
+
+	var affected int64 = 1
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO login_failures"):
+		key := args[0].Value.(string) + "/" + args[1].Value.(string)
+		if c.db.failures[key] == nil {
+			c.db.failures[key] = &data.LoginFailure{
+				Scope:        args[0].Value.(string),
+				Subject:      args[1].Value.(string),
+				LastFailedAt: args[2].Value.(time.Time),
+			}
+		}
+	case strings.HasPrefix(query, "UPDATE login_failures SET failures = $3"):
+		f := c.db.failures[args[0].Value.(string)+"/"+args[1].Value.(string)]
+		f.Failures = int(args[2].Value.(int64))
+		f.LastFailedAt = args[3].Value.(time.Time)
+		f.BlockedUntil = nil
+		if until, ok := args[4].Value.(time.Time); ok {
+			f.BlockedUntil = &until
+		}
+		f.Locked = args[5].Value.(bool)
+	case strings.HasPrefix(query, "UPDATE login_failures SET failures = GREATEST"):
+		f := c.db.failures[args[0].Value.(string)+"/"+args[1].Value.(string)]
+		if f == nil {
+			break
+		}
+		if f.Failures > 0 {
+			f.Failures--
+		}
+		if until, ok := args[2].Value.(time.Time); ok && f.BlockedUntil != nil && f.BlockedUntil.Equal(until) {
+			f.BlockedUntil = nil
+		}
+	case strings.HasPrefix(query, "DELETE FROM login_failures"):
+		delete(c.db.failures, args[0].Value.(string)+"/"+args[1].Value.(string))
+	case strings.HasPrefix(query, "INSERT INTO tokens"):
+		c.db.tokens[string(args[0].Value.([]byte))] = args[3].Value.(string)
+	case strings.HasPrefix(query, "DELETE FROM tokens WHERE scope = $1"):
+		for hash, scope := range c.db.tokens {
+			if scope == args[0].Value.(string) {
+				delete(c.db.tokens, hash)
+			}
+		}
+	case strings.HasPrefix(query, "INSERT INTO user_mfa"):
+		if c.db.mfa != nil && c.db.mfa.Enabled {
+			affected = 0
+			break
+		}
+		c.db.mfa = &data.MFA{
+			UserID:    args[0].Value.(int64),
+			Secret:    args[1].Value.(string),
+			CreatedAt: args[2].Value.(time.Time),
+		}
+	case strings.HasPrefix(query, "UPDATE user_mfa SET last_counter = $2"):
+		counter := args[1].Value.(int64)
+		if c.db.mfa == nil || c.db.mfa.LastCounter >= counter {
+			affected = 0
+			break
+		}
+		c.db.mfa.LastCounter = counter
+		c.db.mfa.Enabled = true
+	case strings.HasPrefix(query, "DELETE FROM mfa_recovery_codes"):
+		c.db.recovery = map[string]bool{}
+	case strings.HasPrefix(query, "INSERT INTO mfa_recovery_codes"):
+		c.db.recovery[string(args[0].Value.([]byte))] = false
+	case strings.HasPrefix(query, "UPDATE mfa_recovery_codes SET used_at"):
+		hash := string(args[0].Value.([]byte))
+		used, ok := c.db.recovery[hash]
+		if !ok || used {
+			affected = 0
+			break
+		}
+		c.db.recovery[hash] = true
+	default:
+		return nil, errors.New("unexpected statement: " + query)
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *loginConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	defer c.lock()()
+
+	query = strings.Join(strings.Fields(query), " ")
+
+	switch {
+	case strings.HasPrefix(query, "SELECT scope, subject, failures"):
+		f := c.db.failures[args[0].Value.(string)+"/"+args[1].Value.(string)]
+		var blockedUntil driver.Value
+		if f.BlockedUntil != nil {
+			blockedUntil = *f.BlockedUntil
+		}
+		return &loginRows{rows: [][]driver.Value{
+			{f.Scope, f.Subject, int64(f.Failures), f.LastFailedAt, blockedUntil, f.Locked},
+		}}, nil
+	case strings.Contains(query, "FROM users WHERE email = $1"):
+		rows := &loginRows{}
+		if args[0].Value.(string) == c.db.email {
+			rows.rows = [][]driver.Value{{int64(1), time.Now(), "Alice", c.db.email, c.db.hash, true, int64(1)}}
+		}
+		return rows, nil
+	case strings.Contains(query, "FROM user_mfa"):
+		rows := &loginRows{}
+		if mfa := c.db.mfa; mfa != nil {
+			rows.rows = [][]driver.Value{{mfa.UserID, mfa.Secret, mfa.Enabled, mfa.LastCounter, mfa.CreatedAt}}
+		}
+		return rows, nil
+	case strings.Contains(query, "INNER JOIN tokens"):
+		rows := &loginRows{}
+		if c.db.tokens[string(args[0].Value.([]byte))] == args[1].Value.(string) {
+			rows.rows = [][]driver.Value{{int64(1), time.Now(), "Alice", c.db.email, c.db.hash, true, int64(1)}}
+		}
+		return rows, nil
+	}
+
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type loginRows struct {
+	rows [][]driver.Value
+}
+
+func (r *loginRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"unused"}
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *loginRows) Close() error { return nil }
+
+func (r *loginRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func newLoginTestApplication(db *sql.DB) *application {
+	app := newTestApplication("production")
+	app.models = data.NewModels(db)
+	app.config.login.freeAttempts = 3
+	app.config.login.maxFailures = 10
+	app.config.login.backoffBase = time.Minute
+	app.config.login.backoffMax = time.Hour
+	app.config.login.lockoutDuration = time.Hour
+	app.config.login.failureWindow = time.Hour
+	return app
+}
+
+func login(app *application, email, password string) (int, string) {
+	body := fmt.Sprintf(`{"email": %q, "password": %q}`, email, password)
+	r := httptest.NewRequest(http.MethodPost, "/v1/tokens/authentication", strings.NewReader(body))
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	rr := httptest.NewRecorder()
+	app.createAuthenticationTokenHandler(rr, r)
+
+	var resp struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	return rr.Code, resp.Code
+}
+
+func TestConcurrentBadLogins(t *testing.T) {
+	fake, db := newLoginDB(t, "alice@example.com", "pa55word1234")
+	app := newLoginTestApplication(db)
+
+	const attempts = 20
+
+	var wg sync.WaitGroup
+	codes := make(chan int, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := login(app, "alice@example.com", "wrongpassword")
+			codes <- code
+		}()
+	}
+
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+
+	// 처음 세 번은 지연 없이 허용되고 네 번째 실패가 차단을 설정하므로, 동시에 보내더라도
+	// 정확히 네 번만 비밀번호를 확인하고 나머지는 거부되어야 합니다.
+	want := map[int]int{http.StatusUnauthorized: 4, http.StatusTooManyRequests: attempts - 4}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("got responses %v; want %v", counts, want)
+	}
+
+	if f := fake.failure(data.LoginScopeAccount, "alice@example.com"); f == nil || f.Failures != 4 {
+		t.Errorf("account failures = %+v; want 4", f)
+	}
+	if f := fake.failure(data.LoginScopeIP, "192.0.2.1"); f == nil || f.Failures != 4 {
+		t.Errorf("ip failures = %+v; want 4", f)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	fake, db := newLoginDB(t, "alice@example.com", "pa55word1234")
+	app := newLoginTestApplication(db)
+	app.config.login.freeAttempts = 10
+	app.config.login.maxFailures = 2
+
+	tests := []struct {
+		password string
+		wantCode int
+		wantErr  string
+	}{
+		{"wrongpassword", http.StatusUnauthorized, "invalid_credentials"},
+		{"wrongpassword", http.StatusUnauthorized, "invalid_credentials"},
+		// 잠긴 계정은 올바른 비밀번호로도 로그인할 수 없습니다.
+		{"pa55word1234", http.StatusTooManyRequests, "account_locked"},
+	}
+
+	for i, tt := range tests {
+		code, errCode := login(app, "alice@example.com", tt.password)
+		if code != tt.wantCode || errCode != tt.wantErr {
+			t.Errorf("attempt %d: got %d %q; want %d %q", i+1, code, errCode, tt.wantCode, tt.wantErr)
+		}
+	}
+
+	if f := fake.failure(data.LoginScopeAccount, "alice@example.com"); f == nil || !f.Locked || f.Failures != 2 {
+		t.Errorf("account = %+v; want locked after 2 failures", f)
+	}
+}
+
+func TestLoginForgivesSuccess(t *testing.T) {
+	fake, db := newLoginDB(t, "alice@example.com", "pa55word1234")
+	app := newLoginTestApplication(db)
+	app.config.login.freeAttempts = 2
+
+	for _, password := range []string{"wrongpassword", "wrongpassword"} {
+		if code, _ := login(app, "alice@example.com", password); code != http.StatusUnauthorized {
+			t.Fatalf("bad login: got %d; want 401", code)
+		}
+	}
+
+	// 세 번째 시도는 성공하더라도 미리 실패로 기록되어 차단을 설정하지만, 성공하면 이를 되돌려야 합니다.
+	if code, _ := login(app, "alice@example.com", "pa55word1234"); code != http.StatusCreated {
+		t.Fatalf("good login: got %d; want 201", code)
+	}
+
+	if f := fake.failure(data.LoginScopeAccount, "alice@example.com"); f != nil {
+		t.Errorf("account record = %+v; want it deleted", f)
+	}
+
+	ip := fake.failure(data.LoginScopeIP, "192.0.2.1")
+	if ip == nil || ip.Failures != 2 || ip.BlockedUntil != nil {
+		t.Fatalf("ip record = %+v; want 2 failures and no block", ip)
+	}
+
+	// 성공한 로그인은 IP 주소의 실패 기록을 초기화하지 않으므로 다음 실패는 세 번째 실패로 집계됩니다.
+	if code, _ := login(app, "bob@example.com", "wrongpassword"); code != http.StatusUnauthorized {
+		t.Fatalf("bad login: got %d; want 401", code)
+	}
+	if code, errCode := login(app, "carol@example.com", "wrongpassword"); code != http.StatusTooManyRequests || errCode != "too_many_login_attempts" {
+		t.Errorf("after backoff: got %d %q; want 429 too_many_login_attempts", code, errCode)
+	}
+}
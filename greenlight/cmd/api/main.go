@@ -9,14 +9,18 @@ import (
 	"os"
 	"runtime"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/lib/pq"
+	"greenlight.wook.net/internal/cache"
 	"greenlight.wook.net/internal/data"
 	"greenlight.wook.net/internal/jsonlog"
 	"greenlight.wook.net/internal/mailer"
+	"greenlight.wook.net/internal/migrate"
+	"greenlight.wook.net/internal/storage"
 	"greenlight.wook.net/internal/vcs"
+	"greenlight.wook.net/migrations"
 )
 
 var (
@@ -26,11 +30,19 @@ var (
 type config struct {
 	port int
 	env  string
-	db   struct {
+	// baseURL은 이메일에 포함되는 링크를 만들 때 사용하는 API 서버의 공개 주소입니다.
+	baseURL string
+	// shutdownTimeout은 종료 신호를 받은 뒤 진행 중인 요청과 백그라운드 작업을 기다리는 최대 시간입니다.
+	shutdownTimeout time.Duration
+	// shutdownDrainDelay는 준비 상태 검사가 503을 반환하기 시작한 뒤 서버를 닫기 전까지 기다리는 시간입니다.
+	// 로드 밸런서가 준비 상태 변화를 감지하고 이 인스턴스를 대상에서 제외할 시간을 줍니다.
+	shutdownDrainDelay time.Duration
+	db                 struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
+		autoMigrate  bool
 	}
 	limiter struct {
 		rps     float64
@@ -45,17 +57,57 @@ type config struct {
 		sender   string
 	}
 	// cors 구조체 및 trustedOrigins 필드를 추가합니다.
+	// trustedOrigins에는 "https://*.example.com" 같은 하위 도메인 패턴도 사용할 수 있습니다.
 	cors struct {
-		trustedOrigins []string
+		trustedOrigins   []string
+		exposedHeaders   []string
+		maxAge           time.Duration
+		allowCredentials bool
+	}
+	// 업로드된 파일을 저장할 백엔드 설정입니다. backend는 "local" 또는 "s3"입니다.
+	storage struct {
+		backend  string
+		localDir string
+		localURL string
+		s3       storage.S3Config
+	}
+	posters struct {
+		maxBytes int64
+	}
+	// 인증 토큰 발급 시 무차별 대입 공격을 막기 위한 설정입니다.
+	login struct {
+		freeAttempts    int
+		maxFailures     int
+		backoffBase     time.Duration
+		backoffMax      time.Duration
+		lockoutDuration time.Duration
+		failureWindow   time.Duration
+	}
+	// movie 조회 결과를 캐시할 백엔드 설정입니다. backend는 "none", "memory" 또는 "redis"입니다.
+	cache struct {
+		backend string
+		size    int
+		ttl     time.Duration
+		redis   cache.RedisConfig
 	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *jsonlog.Logger
+	db       *sql.DB
+	models   data.Models
+	mailer   mailer.Mailer
+	storage  storage.Storage
+	migrator *migrate.Migrator
+	// corsOrigins는 cfg.cors.trustedOrigins로 초기화되며 관리자 엔드포인트로 교체할 수 있습니다.
+	corsOrigins *originList
+	// tasks는 app.background()로 시작한 작업을 추적하며 serve()가 종료 시 기다립니다.
+	tasks *taskTracker
+	// draining은 serve()가 종료 신호를 받은 뒤 true로 설정되며, 준비 상태 검사가 503을 반환하게 합니다.
+	draining atomic.Bool
+	// now는 TOTP 코드를 확인할 때 사용하는 현재 시각입니다. 테스트에서는 고정된 시각으로 바꿉니다.
+	now func() time.Time
 }
 
 func main() {
@@ -64,11 +116,16 @@ func main() {
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production")
 
+	flag.StringVar(&cfg.baseURL, "base-url", "http://localhost:4000", "Public base URL of the API, used in email links")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 20*time.Second, "How long to wait for requests and background tasks on shutdown")
+	flag.DurationVar(&cfg.shutdownDrainDelay, "shutdown-drain-delay", 5*time.Second, "How long to keep serving after readiness starts failing on shutdown")
+
 	flag.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
 
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	flag.BoolVar(&cfg.db.autoMigrate, "auto-migrate", false, "Apply pending database migrations on startup")
 
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "limiter 초당 최대 요청 수")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "limiter 최대 버스트")
@@ -84,6 +141,39 @@ func main() {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
 	})
+	flag.Func("cors-exposed-headers", "Response headers exposed to CORS requests (space separated)", func(val string) error {
+		cfg.cors.exposedHeaders = strings.Fields(val)
+		return nil
+	})
+	flag.DurationVar(&cfg.cors.maxAge, "cors-max-age", time.Hour, "How long browsers may cache preflight responses")
+	flag.BoolVar(&cfg.cors.allowCredentials, "cors-allow-credentials", false, "Allow credentialed CORS requests from trusted origins")
+
+	flag.StringVar(&cfg.storage.backend, "storage-backend", "local", "File storage backend (local|s3)")
+	flag.StringVar(&cfg.storage.localDir, "storage-local-dir", "./uploads", "Directory for the local storage backend")
+	flag.StringVar(&cfg.storage.localURL, "storage-local-url", "/static", "Base URL the local storage directory is served from")
+	flag.StringVar(&cfg.storage.s3.Endpoint, "storage-s3-endpoint", "", "S3 compatible endpoint URL")
+	flag.StringVar(&cfg.storage.s3.Region, "storage-s3-region", "us-east-1", "S3 region")
+	flag.StringVar(&cfg.storage.s3.Bucket, "storage-s3-bucket", "", "S3 bucket")
+	flag.StringVar(&cfg.storage.s3.AccessKey, "storage-s3-access-key", "", "S3 access key")
+	flag.StringVar(&cfg.storage.s3.SecretKey, "storage-s3-secret-key", "", "S3 secret key")
+	flag.StringVar(&cfg.storage.s3.PublicURL, "storage-s3-public-url", "", "Public base URL for S3 objects")
+
+	flag.IntVar(&cfg.login.freeAttempts, "login-free-attempts", 3, "Failed logins allowed before backoff starts")
+	flag.IntVar(&cfg.login.maxFailures, "login-max-failures", 10, "Failed logins before an account is locked")
+	flag.DurationVar(&cfg.login.backoffBase, "login-backoff-base", time.Second, "Initial delay after repeated failed logins")
+	flag.DurationVar(&cfg.login.backoffMax, "login-backoff-max", 15*time.Minute, "Maximum delay between failed logins")
+	flag.DurationVar(&cfg.login.lockoutDuration, "login-lockout-duration", 30*time.Minute, "How long an account stays locked")
+	flag.DurationVar(&cfg.login.failureWindow, "login-failure-window", time.Hour, "Failed logins older than this are forgotten")
+
+	flag.StringVar(&cfg.cache.backend, "cache-backend", "memory", "Movie cache backend (none|memory|redis)")
+	flag.IntVar(&cfg.cache.size, "cache-size", 1000, "Maximum number of entries in the memory cache")
+	flag.DurationVar(&cfg.cache.ttl, "cache-ttl", time.Minute, "How long cached movies are kept")
+	flag.StringVar(&cfg.cache.redis.Addr, "cache-redis-addr", "localhost:6379", "Redis compatible server address")
+	flag.StringVar(&cfg.cache.redis.Password, "cache-redis-password", "", "Redis password")
+	flag.IntVar(&cfg.cache.redis.DB, "cache-redis-db", 0, "Redis database number")
+	flag.StringVar(&cfg.cache.redis.Prefix, "cache-redis-prefix", "greenlight:", "Prefix added to every Redis key")
+
+	flag.Int64Var(&cfg.posters.maxBytes, "poster-max-bytes", 5*1024*1024, "Maximum poster upload size in bytes")
 
 	// Create a new version boolean flag with the default value of false.
 	displayVersion := flag.Bool("version", false, "Display version and exit")
@@ -105,6 +195,22 @@ func main() {
 	defer db.Close()
 	logger.PrintInfo("데이터베이스 연결 풀 설정됨", nil)
 
+	// 바이너리에 포함된 SQL 파일로 마이그레이션 러너를 생성합니다.
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	// "api migrate ..." 형태로 실행된 경우 서버를 시작하지 않고 마이그레이션 명령만 처리합니다.
+	// 마이그레이션에는 데이터베이스만 필요하므로 다른 백엔드를 만들기 전에 처리합니다.
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(migrator, flag.Args()[1:])
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		return
+	}
+
 	expvar.NewString("version").Set(version)
 
 	// 활성 고루틴의 수를 게시합니다.
@@ -120,11 +226,51 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	store, err := openStorage(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	corsOrigins, err := newOriginList(cfg.cors.trustedOrigins)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	models := data.NewModels(db)
+
+	movieCache, err := openCache(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	// 캐시가 설정된 경우 movie 모델을 read-through 캐시로 감싸고 적중률 통계를 게시합니다.
+	if movieCache != nil {
+		cached := data.NewCachedMovieModel(models.Movies, movieCache, cfg.cache.ttl)
+		models.Movies = cached
+
+		expvar.Publish("movie_cache", expvar.Func(func() any {
+			return cached.Stats.Snapshot()
+		}))
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:      cfg,
+		logger:      logger,
+		db:          db,
+		models:      models,
+		mailer:      mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage:     store,
+		migrator:    migrator,
+		corsOrigins: corsOrigins,
+		tasks:       newTaskTracker(),
+		now:         time.Now,
+	}
+
+	if cfg.db.autoMigrate {
+		err = app.autoMigrate()
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
 	}
 
 	err = app.serve()
@@ -133,6 +279,45 @@ func main() {
 	}
 }
 
+// openStorage()는 설정된 백엔드에 맞는 storage.Storage 구현을 생성합니다.
+func openStorage(cfg config) (storage.Storage, error) {
+	switch cfg.storage.backend {
+	case "local":
+		return storage.NewLocal(cfg.storage.localDir, cfg.storage.localURL)
+	case "s3":
+		return storage.NewS3(cfg.storage.s3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.storage.backend)
+	}
+}
+
+// openCache()는 설정된 백엔드에 맞는 cache.Cache 구현을 생성합니다. "none"이면 nil을 반환합니다.
+func openCache(cfg config) (cache.Cache, error) {
+	switch cfg.cache.backend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return cache.NewLRU(cfg.cache.size), nil
+	case "redis":
+		c, err := cache.NewRedis(cfg.cache.redis)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err = c.Ping(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.cache.backend)
+	}
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/totp"
+	"greenlight.wook.net/internal/validator"
+)
+
+const (
+	// 인증 앱에 표시되는 발급자 이름입니다.
+	mfaIssuer = "Greenlight"
+	// mfa-pending 토큰은 두 번째 인증 요소를 입력하는 동안에만 필요하므로 유효 기간을 짧게 둡니다.
+	mfaPendingTTL = 5 * time.Minute
+)
+
+// enrollMFAHandler()는 비밀번호를 다시 확인한 뒤 새 TOTP 비밀 키를 만들고 인증 앱에 등록할 수 있는
+// 프로비저닝 URI를 반환합니다. 탈취한 인증 토큰만으로 비밀 키를 발급받거나 바꿀 수 없도록 비밀번호를 요구합니다.
+// 설정은 confirmMFAHandler()로 첫 코드를 확인할 때까지 비활성 상태로 남아 로그인에 영향을 주지 않습니다.
+func (app *application) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if app.contextGetApiKey(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Password != "", "password", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.MFA.Enroll(r.Context(), user.ID, secret, app.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.failedValidationResponse(w, r, map[string]string{"mfa": "multi-factor authentication is already enabled"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"mfa": map[string]string{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(secret, mfaIssuer, user.Email),
+	}}
+
+	err = app.writeJSON(w, r, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmMFAHandler()는 인증 앱이 만든 첫 코드를 확인하여 TOTP를 활성화하고 복구 코드를 발급합니다.
+// 복구 코드의 일반 텍스트는 이 응답에서 한 번만 노출됩니다.
+func (app *application) confirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTOTPCode(v, input.Code); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	mfa, err := app.models.MFA.Get(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.failedValidationResponse(w, r, map[string]string{"mfa": "multi-factor authentication enrollment has not been started"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if mfa.Enabled {
+		app.failedValidationResponse(w, r, map[string]string{"mfa": "multi-factor authentication is already enabled"})
+		return
+	}
+
+	ok, err := app.verifyTOTP(r.Context(), mfa, input.Code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !ok {
+		app.failedValidationResponse(w, r, map[string]string{"code": "invalid or expired code"})
+		return
+	}
+
+	codes, err := app.models.MFA.NewRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// disableMFAHandler()는 비밀번호를 다시 확인한 뒤 TOTP 설정과 복구 코드를 삭제합니다.
+func (app *application) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if app.contextGetApiKey(r) != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Password != "", "password", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	err = app.models.MFA.Delete(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "multi-factor authentication has been disabled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createMFAAuthenticationTokenHandler()는 mfa-pending 토큰과 TOTP 코드 또는 복구 코드를 받아
+// 일반 인증 토큰을 발급합니다. 잘못된 코드는 비밀번호 실패와 같은 방식으로 집계되어 대입 공격을 막습니다.
+func (app *application) createMFAAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.MFAToken != "", "mfa_token", "must be provided")
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "must provide either a code or a recovery code")
+	if input.Code != "" {
+		data.ValidateTOTPCode(v, input.Code)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeMFAPending, input.MFAToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	account, ip, err := loginSubjects(r, user.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attempt := app.beginLoginAttempt(w, r, account, ip)
+	if attempt == nil {
+		return
+	}
+
+	mfa, err := app.models.MFA.Get(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var ok bool
+
+	if mfa != nil && mfa.Enabled {
+		if input.Code != "" {
+			ok, err = app.verifyTOTP(r.Context(), mfa, input.Code)
+		} else {
+			ok, err = app.models.MFA.UseRecoveryCode(r.Context(), user.ID, input.RecoveryCode)
+		}
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !ok {
+		app.loginFailed(attempt, user)
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	err = app.models.LoginFailures.Forgive(r.Context(), attempt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// 교환이 끝난 mfa-pending 토큰은 다시 사용할 수 없도록 삭제합니다.
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeMFAPending, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.issueAuthenticationToken(w, r, user)
+}
+
+// verifyTOTP()는 코드가 현재 시각 기준으로 유효하고 아직 사용되지 않았는지 확인합니다.
+func (app *application) verifyTOTP(ctx context.Context, mfa *data.MFA, code string) (bool, error) {
+	counter, ok, err := totp.Validate(mfa.Secret, code, app.now())
+	if err != nil || !ok {
+		return false, err
+	}
+
+	err = app.models.MFA.UseCounter(ctx, mfa.UserID, counter)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// issueAuthenticationToken()은 만료 시간이 24시간이고 범위가 '인증'인 새 토큰을 생성하여 응답으로 보냅니다.
+func (app *application) issueAuthenticationToken(w http.ResponseWriter, r *http.Request, user *data.User) {
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 24*time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	//  토큰을 JSON으로 인코딩하고 201 생성됨 상태 코드와 함께 응답으로 전송합니다.
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/totp"
+)
+
+func TestEnrollMFARequiresPassword(t *testing.T) {
+	// 모델에 데이터베이스가 없으므로 비밀번호 확인을 통과하면 TOTP 비밀 키를 저장하다 패닉이 발생합니다.
+	app := newTestApplication("production")
+
+	user := &data.User{ID: 1, Email: "alice@example.com", Activated: true}
+	err := user.Password.Set("pa55word1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing password", `{}`, http.StatusUnprocessableEntity},
+		{"wrong password", `{"password": "wrongpassword"}`, http.StatusUnauthorized},
+		{"unknown field", `{"password": "pa55word1234", "secret": "AAAA"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/users/me/mfa", strings.NewReader(tt.body))
+			r = app.contextSetUser(r, user)
+
+			rr := httptest.NewRecorder()
+			app.enrollMFAHandler(rr, r)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+// postMFA()는 핸들러에 JSON 본문을 보내고 응답을 dst로 디코딩합니다. user가 있으면 요청 컨텍스트에 설정합니다.
+func postMFA(t *testing.T, app *application, handler http.HandlerFunc, user *data.User, body string, dst any) int {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.RemoteAddr = "192.0.2.1:1234"
+	if user != nil {
+		r = app.contextSetUser(r, user)
+	}
+
+	rr := httptest.NewRecorder()
+	handler(rr, r)
+
+	if dst != nil {
+		err := json.NewDecoder(rr.Body).Decode(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rr.Code
+}
+
+func TestMFAFlow(t *testing.T) {
+	fake, db := newLoginDB(t, "alice@example.com", "pa55word1234")
+	app := newLoginTestApplication(db)
+	app.config.login.freeAttempts = 10
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	app.now = func() time.Time { return now }
+
+	user := &data.User{ID: 1, Email: "alice@example.com", Activated: true}
+	err := user.Password.Set("pa55word1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := func() string {
+		t.Helper()
+		c, err := totp.Code(fake.mfaState().Secret, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// 비밀번호로 로그인하여 mfa-pending 토큰을 받습니다.
+	mfaToken := func() string {
+		t.Helper()
+		var resp struct {
+			MFARequired bool `json:"mfa_required"`
+			MFAToken    struct {
+				Token string `json:"token"`
+			} `json:"mfa_token"`
+		}
+		status := postMFA(t, app, app.createAuthenticationTokenHandler, nil, `{"email": "alice@example.com", "password": "pa55word1234"}`, &resp)
+		if status != http.StatusOK || !resp.MFARequired || resp.MFAToken.Token == "" {
+			t.Fatalf("password login: status %d, mfa_required %t; want 200 with an mfa token", status, resp.MFARequired)
+		}
+		return resp.MFAToken.Token
+	}
+
+	exchange := func(body string) int {
+		t.Helper()
+		var resp struct {
+			Token *data.Token `json:"authentication_token"`
+		}
+		status := postMFA(t, app, app.createMFAAuthenticationTokenHandler, nil, body, &resp)
+		if status == http.StatusCreated && (resp.Token == nil || resp.Token.Plaintext == "") {
+			t.Fatal("missing authentication token")
+		}
+		return status
+	}
+
+	t.Run("enroll", func(t *testing.T) {
+		var resp struct {
+			MFA map[string]string `json:"mfa"`
+		}
+		status := postMFA(t, app, app.enrollMFAHandler, user, `{"password": "pa55word1234"}`, &resp)
+		if status != http.StatusCreated {
+			t.Fatalf("got status %d; want %d", status, http.StatusCreated)
+		}
+
+		mfa := fake.mfaState()
+		if mfa == nil || mfa.Enabled {
+			t.Fatalf("mfa = %+v; want a disabled enrollment", mfa)
+		}
+		if resp.MFA["secret"] != mfa.Secret {
+			t.Errorf("secret = %q; want the stored secret %q", resp.MFA["secret"], mfa.Secret)
+		}
+		if !mfa.CreatedAt.Equal(now) {
+			t.Errorf("created_at = %s; want %s", mfa.CreatedAt, now)
+		}
+
+		// 아직 확인하지 않았으므로 비밀번호만으로 로그인할 수 있습니다.
+		if status, _ := login(app, "alice@example.com", "pa55word1234"); status != http.StatusCreated {
+			t.Errorf("login before confirmation: got status %d; want %d", status, http.StatusCreated)
+		}
+	})
+
+	var recoveryCodes []string
+
+	t.Run("confirm", func(t *testing.T) {
+		expired, err := totp.Code(fake.mfaState().Secret, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := postMFA(t, app, app.confirmMFAHandler, user, `{"code": "`+expired+`"}`, nil); status != http.StatusUnprocessableEntity {
+			t.Errorf("expired code: got status %d; want %d", status, http.StatusUnprocessableEntity)
+		}
+
+		var resp struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		status := postMFA(t, app, app.confirmMFAHandler, user, `{"code": "`+code()+`"}`, &resp)
+		if status != http.StatusOK {
+			t.Fatalf("got status %d; want %d", status, http.StatusOK)
+		}
+		if len(resp.RecoveryCodes) != data.RecoveryCodeCount {
+			t.Fatalf("got %d recovery codes; want %d", len(resp.RecoveryCodes), data.RecoveryCodeCount)
+		}
+		if mfa := fake.mfaState(); !mfa.Enabled || mfa.LastCounter != totp.Counter(now) {
+			t.Errorf("mfa = %+v; want enabled at counter %d", mfa, totp.Counter(now))
+		}
+		recoveryCodes = resp.RecoveryCodes
+	})
+
+	t.Run("login with totp", func(t *testing.T) {
+		token := mfaToken()
+
+		// 확인에 사용한 코드는 같은 주기 안에서 다시 사용할 수 없습니다.
+		if status := exchange(`{"mfa_token": "` + token + `", "code": "` + code() + `"}`); status != http.StatusUnauthorized {
+			t.Errorf("replayed code: got status %d; want %d", status, http.StatusUnauthorized)
+		}
+
+		now = now.Add(totp.Period)
+		if status := exchange(`{"mfa_token": "` + token + `", "code": "` + code() + `"}`); status != http.StatusCreated {
+			t.Fatalf("got status %d; want %d", status, http.StatusCreated)
+		}
+		if n := fake.tokenCount(data.ScopeMFAPending); n != 0 {
+			t.Errorf("%d mfa-pending tokens left; want 0", n)
+		}
+
+		// 교환한 mfa-pending 토큰도, 사용한 코드도 다시 받아들이지 않습니다.
+		if status := exchange(`{"mfa_token": "` + token + `", "code": "` + code() + `"}`); status != http.StatusUnauthorized {
+			t.Errorf("reused mfa token: got status %d; want %d", status, http.StatusUnauthorized)
+		}
+		if status := exchange(`{"mfa_token": "` + mfaToken() + `", "code": "` + code() + `"}`); status != http.StatusUnauthorized {
+			t.Errorf("replayed code with a new mfa token: got status %d; want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("login with recovery code", func(t *testing.T) {
+		body := `{"mfa_token": "` + mfaToken() + `", "recovery_code": "` + strings.ToUpper(recoveryCodes[0]) + `"}`
+		if status := exchange(body); status != http.StatusCreated {
+			t.Fatalf("got status %d; want %d", status, http.StatusCreated)
+		}
+
+		body = `{"mfa_token": "` + mfaToken() + `", "recovery_code": "` + recoveryCodes[0] + `"}`
+		if status := exchange(body); status != http.StatusUnauthorized {
+			t.Errorf("reused recovery code: got status %d; want %d", status, http.StatusUnauthorized)
+		}
+
+		body = `{"mfa_token": "` + mfaToken() + `", "recovery_code": "` + recoveryCodes[1] + `"}`
+		if status := exchange(body); status != http.StatusCreated {
+			t.Errorf("unused recovery code: got status %d; want %d", status, http.StatusCreated)
+		}
+	})
+}
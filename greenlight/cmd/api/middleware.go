@@ -1,9 +1,13 @@
 package main
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"errors"
 	"expvar"
 	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"strconv"
@@ -118,7 +122,18 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// 이를 구성 요소로 분리하고, 헤더가 예상한 형식이 아닌 경우에는 invalidAuthenticationTokenResponse() 헬퍼를 사용하여
 		// 401 Unauthorized 응답을 반환합니다. (이 헬퍼는 곧 생성할 것입니다.)
 		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		if len(headerParts) != 2 {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		// 머신 클라이언트는 "ApiKey <key>" 형식으로 장기 API 키를 보낼 수 있습니다.
+		if headerParts[0] == "ApiKey" {
+			app.authenticateApiKey(w, r, headerParts[1], next)
+			return
+		}
+
+		if headerParts[0] != "Bearer" {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
@@ -139,7 +154,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// 인증 토큰과 관련된 사용자의 세부 정보를 검색합니다.
 		// 일치하는 레코드가 없는 경우, invalidAuthenticationTokenResponse() 헬퍼를 호출합니다.
 		// 중요: 여기에서 첫 번째 매개변수로 ScopeAuthentication을 사용하고 있음에 주목하세요.
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -159,6 +174,33 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateApiKey()는 API 키의 소유자와 키 자체를 요청 컨텍스트에 추가한 뒤 다음 핸들러를 호출합니다.
+// 키의 권한 범위는 requirePermission()에서 확인합니다.
+func (app *application) authenticateApiKey(w http.ResponseWriter, r *http.Request, plaintext string, next http.Handler) {
+	v := validator.New()
+
+	if data.ValidateApiKeyPlaintext(v, plaintext); !v.Valid() {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	user, key, err := app.models.ApiKeys.GetForKey(r.Context(), plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetApiKey(r, key)
+
+	next.ServeHTTP(w, r)
+}
+
 // 사용자가 익명이 아닌지 확인하기 위해 새로운 requireAuthenticatedUser() 미들웨어를 생성합니다.
 func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -194,7 +236,7 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 		user := app.contextGetUser(r)
 
 		// 사용자에 대한 permissions 슬라이스를 가져옵니다.
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -205,6 +247,13 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 			app.notPermittedResponse(w, r)
 			return
 		}
+
+		// API 키로 인증된 요청은 소유자의 현재 권한과 키에 부여된 권한을 모두 만족해야 합니다.
+		// 소유자의 권한이 나중에 회수되면 키의 권한도 함께 사라집니다.
+		if key := app.contextGetApiKey(r); key != nil && !key.Permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
 		// 그렇지 않으면 필요한 권한이 있으므로 체인의 다음 핸들러를 호출합니다.
 		next.ServeHTTP(w, r)
 	}
@@ -212,30 +261,19 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 	return app.requireActivatedUser(fn)
 }
 
+// enableCORS()는 요청 경로에 맞는 CORS 정책을 찾아 적용합니다. 정책은 미들웨어를 만들 때
+// 한 번만 구성하며, 신뢰할 수 있는 출처 목록만 실행 중에 교체될 수 있습니다.
 func (app *application) enableCORS(next http.Handler) http.Handler {
+	routes, fallback := app.corsPolicies()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Origin")
 		w.Header().Add("Vary", "Access-Control-Request-Method")
-		origin := r.Header.Get("Origin")
-
-		if origin != "" {
-			for i := range app.config.cors.trustedOrigins {
-				if origin == app.config.cors.trustedOrigins[i] {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					// 요청에 HTTP 메서드 OPTIONS가 있고 "Access-Control-Request-Method" 헤더가
-					// 포함되어 있는지 확인합니다. 포함되어 있으면 사전 점검 요청으로 처리합니다.
-					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
-						//  앞서 설명한 대로 필요한 비행 전 응답 헤더를 설정합니다.
-						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-						//  200 OK 상태와 함께 헤더를 작성하고 추가 작업 없이 미들웨어에서 반환합니다.
-						w.WriteHeader(http.StatusOK)
-						return
-					}
-					break
-				}
-			}
+
+		if corsPolicyFor(routes, fallback, r.URL.Path).apply(w, r) {
+			return
 		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -267,3 +305,271 @@ func (app *application) metrics(next http.Handler) http.Handler {
 		totalResponsesSentByStatus.Add(strconv.Itoa(metrics.Code), 1)
 	})
 }
+
+// 응답 본문이 이보다 작으면 압축으로 얻는 이득보다 비용이 크므로 압축하지 않습니다.
+const minCompressSize = 1024
+
+var (
+	gzipWriterPool = sync.Pool{New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}}
+	zlibWriterPool = sync.Pool{New: func() any {
+		return zlib.NewWriter(io.Discard)
+	}}
+)
+
+// compress() 미들웨어는 Accept-Encoding 헤더에 따라 응답을 gzip 또는 deflate로 압축합니다.
+// HTTP의 "deflate" 인코딩은 zlib 형식(RFC 1950)을 의미하므로 compress/zlib을 사용합니다.
+func (app *application) compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+		defer func() {
+			err := cw.close()
+			if err != nil {
+				app.logError(r, err)
+			}
+		}()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding()은 클라이언트가 허용하는 인코딩 중 q 값이 가장 높은 것을 선택합니다.
+// q 값이 같으면 gzip을 우선하고, 지원하는 인코딩이 없으면 빈 문자열을 반환합니다.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		// q=0은 해당 인코딩을 허용하지 않는다는 의미입니다.
+		if q <= 0 {
+			continue
+		}
+
+		switch name {
+		case "gzip", "deflate":
+		case "*":
+			name = "gzip"
+		default:
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+
+	return best
+}
+
+// compressResponseWriter는 minCompressSize 바이트가 쌓일 때까지 본문과 상태 코드를 보류한 뒤
+// 압축 여부를 결정합니다. 작은 응답은 close() 시점에 압축하지 않고 그대로 씁니다.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buf      []byte
+	writer   io.WriteCloser
+	started  bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressResponseWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if cw.started {
+		if cw.writer != nil {
+			return cw.writer.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < minCompressSize {
+		return len(p), nil
+	}
+
+	err := cw.start(cw.compressible())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// compressible()은 본문이 없는 상태 코드이거나 핸들러가 직접 인코딩을 지정한 경우 false를 반환합니다.
+// 범위 요청에 대한 응답은 압축하면 본문이 Content-Range의 바이트 범위와 맞지 않게 되고,
+// 이미 압축된 형식의 본문은 다시 압축해도 크기가 줄지 않으므로 그대로 보냅니다.
+func (cw *compressResponseWriter) compressible() bool {
+	switch {
+	case cw.status < http.StatusOK, cw.status == http.StatusNoContent, cw.status == http.StatusNotModified:
+		return false
+	case cw.status == http.StatusPartialContent:
+		return false
+	case cw.Header().Get("Content-Encoding") != "":
+		return false
+	case cw.Header().Get("Content-Range") != "", cw.Header().Get("Accept-Ranges") != "":
+		return false
+	}
+
+	// 핸들러가 Content-Type을 지정하지 않았다면 net/http와 같은 방식으로 본문에서 추측합니다.
+	contentType := cw.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+	}
+
+	return compressibleType(contentType)
+}
+
+// compressibleType()은 이미 압축된 미디어 형식이나 압축 파일이 아닌 경우 true를 반환합니다.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "font/woff"):
+		return false
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+		"application/x-bzip2", "application/x-xz", "application/x-7z-compressed", "application/vnd.rar",
+		"application/pdf", "application/wasm":
+		return false
+	}
+
+	return true
+}
+
+// Flush()는 보류 중인 본문과 압축기에 남은 데이터를 클라이언트로 내보냅니다.
+// 아직 압축 여부를 정하지 않았다면 지금까지 쓴 본문만으로 결정합니다.
+func (cw *compressResponseWriter) Flush() {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		err := cw.start(cw.compressible())
+		if err != nil {
+			return
+		}
+	}
+
+	if f, ok := cw.writer.(interface{ Flush() error }); ok {
+		err := f.Flush()
+		if err != nil {
+			return
+		}
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap()은 http.ResponseController가 원래의 ResponseWriter를 찾을 수 있게 합니다.
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// start()는 헤더를 실제로 쓰고 보류 중이던 본문을 내보냅니다.
+func (cw *compressResponseWriter) start(compress bool) error {
+	cw.started = true
+
+	if compress {
+		cw.Header().Set("Content-Encoding", cw.encoding)
+		cw.Header().Del("Content-Length")
+
+		switch cw.encoding {
+		case "gzip":
+			gw := gzipWriterPool.Get().(*gzip.Writer)
+			gw.Reset(cw.ResponseWriter)
+			cw.writer = gw
+		case "deflate":
+			zw := zlibWriterPool.Get().(*zlib.Writer)
+			zw.Reset(cw.ResponseWriter)
+			cw.writer = zw
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.writer != nil {
+		_, err = cw.writer.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close()는 남은 본문을 내보내고 압축기를 닫은 뒤 풀에 반환합니다.
+func (cw *compressResponseWriter) close() error {
+	if !cw.started {
+		// 핸들러가 아무것도 쓰지 않았다면 net/http의 기본 동작에 맡깁니다.
+		if cw.status == 0 {
+			return nil
+		}
+
+		err := cw.start(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cw.writer == nil {
+		return nil
+	}
+
+	err := cw.writer.Close()
+
+	switch w := cw.writer.(type) {
+	case *gzip.Writer:
+		gzipWriterPool.Put(w)
+	case *zlib.Writer:
+		zlibWriterPool.Put(w)
+	}
+	cw.writer = nil
+
+	return err
+}
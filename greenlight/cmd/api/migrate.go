@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"greenlight.wook.net/internal/migrate"
+)
+
+// runMigrate()는 "api migrate up|down|status|to N" 서브커맨드를 처리합니다.
+// 마이그레이션 중에는 실행 시간 제한을 두지 않으므로 context.Background()를 사용합니다.
+func runMigrate(migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: api migrate up|down|status|to N")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+
+	case "down":
+		return migrator.Down(ctx)
+
+	case "to":
+		if len(args) != 2 {
+			return errors.New("usage: api migrate to N")
+		}
+
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+
+		return migrator.To(ctx, version)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Version:\t%d\n", status.Version)
+		fmt.Printf("Dirty:\t\t%t\n\n", status.Dirty)
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, mig := range status.Migrations {
+			fmt.Fprintf(tw, "%06d\t%s\t%t\n", mig.Version, mig.Name, mig.Applied)
+		}
+		return tw.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// autoMigrate()는 -auto-migrate 플래그가 설정된 경우 서버 시작 전에 호출됩니다.
+// 다른 인스턴스가 먼저 잠금을 잡았다면 그 작업이 끝날 때까지 기다린 뒤 남은 마이그레이션만 적용합니다.
+func (app *application) autoMigrate() error {
+	err := app.migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+
+	version, _, err := app.migrator.Version(context.Background())
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("database migrations up to date", map[string]string{
+		"version": strconv.FormatInt(version, 10),
+	})
+
+	return nil
+}
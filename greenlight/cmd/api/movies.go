@@ -25,10 +25,11 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	// movie 변수에는 Movie 구조체에 대한 *포인터*가 포함되어 있습니다.
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:     input.Title,
+		Year:      input.Year,
+		Runtime:   input.Runtime,
+		Genres:    input.Genres,
+		CreatedBy: &app.contextGetUser(r).ID,
 	}
 
 	// 새 유효성 검사기를 초기화합니다.
@@ -44,7 +45,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	// 유효성이 검사된 movies 구조체에 대한 포인터를 전달하여 movies 모델에서
 	// Insert() 메서드를 호출합니다. 그러면 데이터베이스에 레코드가 생성되고
 	// 시스템에서 생성된 정보로 movie 구조체가 업데이트됩니다.
-	err = app.models.Movies.Insert(movie)
+	err = app.models.Movies.Insert(r.Context(), movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -58,7 +59,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	// 201 Created 상태 코드, 응답 본문의 movie 데이터, Location 헤더가
 	// 포함된 JSON 응답을 작성합니다.
-	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -73,7 +74,7 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 	// Get() 메서드를 호출하여 특정 동영상에 대한 데이터를 가져옵니다.
 	// 또한 errors.Is() 함수를 사용하여 data.ErrRecordNotFound 오류를 반환하는지
 	// 확인해야 하며, 이 경우 클라이언트에 404 찾을 수 없음 응답을 전송합니다.
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -84,7 +85,9 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	app.setPosterURLs(movie)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"movie": movie}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -98,7 +101,7 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -160,7 +163,7 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// 모든 ErrEditConflict 오류를 가로채고 새로운 editConflictResponse() 헬퍼를 호출합니다.
-	err = app.models.Movies.Update(movie)
+	err = app.models.Movies.Update(r.Context(), movie, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -171,7 +174,9 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	app.setPosterURLs(movie)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"movie": movie}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -184,7 +189,7 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	err = app.models.Movies.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -195,7 +200,7 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -227,14 +232,17 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// 메타데이터 구조체를 반환값으로 받습니다.
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movies.GetAll(r.Context(), input.Title, input.Genres, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	// 응답 envelope에 메타데이터를 포함합니다.
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	app.setPosterURLs(movies...)
+
+	// 최대 100개의 movie를 반환할 수 있으므로 전체 응답을 버퍼링하지 않고 스트리밍합니다.
+	err = writeJSONStream(app, w, r, http.StatusOK, "movies", movies, envelope{"metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/validator"
+)
+
+// listMovieVersionsHandler()는 movie의 모든 버전 스냅샷을 오래된 순서로 반환합니다.
+func (app *application) listMovieVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// 삭제된 movie의 기록은 함께 삭제되므로 빈 목록 대신 404를 반환하도록 먼저 존재 여부를 확인합니다.
+	_, err = app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	versions, err := app.models.MovieVersions.GetAll(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"versions": versions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// diffMovieVersionsHandler()는 "?from=1&to=3"으로 지정한 두 버전 사이에서 바뀐 필드를 반환합니다.
+// to를 생략하면 현재 버전, from을 생략하면 to의 바로 이전 버전과 비교합니다.
+func (app *application) diffMovieVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	to := app.readInt(qs, "to", int(movie.Version), v)
+	from := app.readInt(qs, "from", to-1, v)
+
+	v.Check(from > 0, "from", "must be greater than zero")
+	v.Check(to > 0, "to", "must be greater than zero")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	versions := make([]*data.MovieVersion, 2)
+
+	for i, number := range []int{from, to} {
+		versions[i], err = app.models.MovieVersions.Get(r.Context(), id, int32(number))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	env := envelope{"diff": map[string]any{
+		"movie_id": id,
+		"from":     from,
+		"to":       to,
+		"changes":  data.DiffMovieVersions(versions[0], versions[1]),
+	}}
+
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revertMovieHandler()는 movie의 내용을 지정한 버전의 스냅샷으로 되돌립니다. 일반 수정과 같은
+// Update() 경로를 사용하므로 그 사이에 다른 요청이 movie를 수정했다면 편집 충돌로 거부되고,
+// 성공하면 이전 버전을 덮어쓰지 않고 새 버전이 추가됩니다. 포스터는 되돌리지 않습니다.
+func (app *application) revertMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := strconv.ParseInt(httprouter.ParamsFromContext(r.Context()).ByName("version"), 10, 32)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	snapshot, err := app.models.MovieVersions.Get(r.Context(), id, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	v.Check(snapshot.Version != movie.Version, "version", "is already the current version")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie.Title = snapshot.Title
+	movie.Year = snapshot.Year
+	movie.Runtime = snapshot.Runtime
+	movie.Genres = snapshot.Genres
+
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Update(r.Context(), movie, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.setPosterURLs(movie)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/i18n"
+	"greenlight.wook.net/internal/thumbnail"
+	"greenlight.wook.net/internal/validator"
+)
+
+const (
+	posterThumbnailWidth  = 200
+	posterThumbnailHeight = 300
+	// 디코딩된 이미지는 픽셀당 최대 4바이트를 차지하므로, 작은 파일로 큰 이미지를 만드는 압축 폭탄이
+	// 메모리를 과도하게 사용하지 않도록 한 변의 길이와 전체 픽셀 수(25MP, 약 100MB)를 함께 제한합니다.
+	posterMaxDimension = 8000
+	posterMaxPixels    = 25_000_000
+)
+
+// 허용하는 MIME 유형과 저장할 때 사용할 파일 확장자입니다. MIME 유형은 클라이언트가 보낸
+// Content-Type 헤더가 아니라 http.DetectContentType()으로 파일 내용을 보고 판단합니다.
+var posterContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// uploadMoviePosterHandler()는 multipart/form-data 요청의 "poster" 필드로 이미지를 받아
+// 원본과 썸네일을 저장소에 저장하고 movie 레코드를 일반적인 낙관적 잠금 경로로 업데이트합니다.
+func (app *application) uploadMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	body, err := app.readPoster(w, r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, errPosterMissing):
+			app.failedValidationResponse(w, r, map[string]string{"poster": "must be provided"})
+		case errors.As(err, &maxBytesError):
+			app.failedValidationErrorsResponse(w, r, map[string]error{"poster": i18n.Errorf("must not be larger than %d bytes", app.config.posters.maxBytes)})
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	ext, ok := posterContentTypes[http.DetectContentType(body)]
+	v.Check(ok, "poster", "must be a JPEG, PNG or GIF image")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// 전체 이미지를 디코딩하기 전에 헤더만 읽어 크기를 확인합니다.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	switch {
+	case err != nil:
+		app.failedValidationResponse(w, r, map[string]string{"poster": "must be a valid image"})
+		return
+	case cfg.Width > posterMaxDimension || cfg.Height > posterMaxDimension:
+		app.failedValidationErrorsResponse(w, r, map[string]error{"poster": i18n.Errorf("must not be larger than %dx%d pixels", posterMaxDimension, posterMaxDimension)})
+		return
+	case int64(cfg.Width)*int64(cfg.Height) > posterMaxPixels:
+		app.failedValidationErrorsResponse(w, r, map[string]error{"poster": i18n.Errorf("must not have more than %d megapixels", posterMaxPixels/1_000_000)})
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		v.AddError("poster", "must be a valid image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var thumb bytes.Buffer
+	err = jpeg.Encode(&thumb, thumbnail.Fit(img, posterThumbnailWidth, posterThumbnailHeight), &jpeg.Options{Quality: 85})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// 이전 포스터를 캐시한 클라이언트가 새 이미지를 받도록 업로드마다 새 키를 사용합니다.
+	name, err := randomHex(16)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	poster := &data.Poster{
+		Key:          fmt.Sprintf("posters/%d/%s%s", movie.ID, name, ext),
+		ThumbnailKey: fmt.Sprintf("posters/%d/%s_thumb.jpg", movie.ID, name),
+	}
+
+	err = app.storage.Put(r.Context(), poster.Key, bytes.NewReader(body), http.DetectContentType(body))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.storage.Put(r.Context(), poster.ThumbnailKey, &thumb, "image/jpeg")
+	if err != nil {
+		app.deletePoster(r, poster)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	previous := movie.Poster
+	movie.Poster = poster
+
+	err = app.models.Movies.Update(r.Context(), movie, app.contextGetUser(r).ID)
+	if err != nil {
+		// 레코드가 업데이트되지 않았으므로 방금 저장한 파일은 더 이상 참조되지 않습니다.
+		app.deletePoster(r, poster)
+
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previous != nil {
+		app.deletePoster(r, previous)
+	}
+
+	app.setPosterURLs(movie)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+var errPosterMissing = errors.New("missing poster field")
+
+// readPoster()는 multipart 본문을 스트리밍으로 읽으면서 "poster" 필드를 찾아 최대
+// app.config.posters.maxBytes 바이트까지만 메모리로 읽습니다.
+func (app *application) readPoster(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	maxBytes := app.config.posters.maxBytes
+
+	// multipart 경계와 파트 헤더를 위해 약간의 여유를 둡니다.
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+64*1024)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, i18n.Errorf("body must be multipart/form-data")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errPosterMissing
+			}
+			return nil, err
+		}
+
+		if part.FormName() != "poster" {
+			continue
+		}
+
+		body, err := io.ReadAll(io.LimitReader(part, maxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(len(body)) > maxBytes {
+			return nil, &http.MaxBytesError{Limit: maxBytes}
+		}
+
+		if len(body) == 0 {
+			return nil, errPosterMissing
+		}
+
+		return body, nil
+	}
+}
+
+// deletePoster()는 더 이상 참조되지 않는 포스터 파일을 삭제합니다.
+// 실패해도 요청 결과에는 영향이 없으므로 오류는 기록만 합니다.
+func (app *application) deletePoster(r *http.Request, poster *data.Poster) {
+	for _, key := range []string{poster.Key, poster.ThumbnailKey} {
+		err := app.storage.Delete(r.Context(), key)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+}
+
+// setPosterURLs()는 데이터베이스에 저장된 키로부터 클라이언트에 노출할 포스터 URL을 채웁니다.
+func (app *application) setPosterURLs(movies ...*data.Movie) {
+	for _, movie := range movies {
+		if movie.Poster == nil {
+			continue
+		}
+		movie.Poster.URL = app.storage.URL(movie.Poster.Key)
+		movie.Poster.ThumbnailURL = app.storage.URL(movie.Poster.ThumbnailKey)
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
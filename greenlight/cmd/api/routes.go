@@ -3,6 +3,8 @@ package main
 import (
 	"expvar"
 	"net/http"
+	"path/filepath"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -15,20 +17,48 @@ func (app *application) routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthz/live", app.livenessHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthz/ready", app.readinessHandler)
 
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updateMovieHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:write", app.deleteMovieHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.uploadMoviePosterHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/versions", app.requirePermission("movies:read", app.listMovieVersionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/diff", app.requirePermission("movies:read", app.diffMovieVersionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/revert/:version", app.requirePermission("movies:write", app.revertMovieHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me", app.requireAuthenticatedUser(app.deleteCurrentUserHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/export", app.requireAuthenticatedUser(app.requestExportHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/exports/:token", app.downloadExportHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/admin/users/:id/unlock", app.requirePermission("system:admin", app.unlockUserHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/mfa", app.requirePermission("movies:write", app.enrollMFAHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/mfa/confirm", app.requirePermission("movies:write", app.confirmMFAHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/mfa", app.requireAuthenticatedUser(app.disableMFAHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/mfa", app.createMFAAuthenticationTokenHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/api-keys", app.requireActivatedUser(app.listApiKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/api-keys", app.requireActivatedUser(app.createApiKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/api-keys/:id", app.requireActivatedUser(app.deleteApiKeyHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/cors/origins", app.requirePermission("system:admin", app.showCORSOriginsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/cors/origins", app.requirePermission("system:admin", app.updateCORSOriginsHandler))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
+	// 로컬 저장소를 사용하고 URL이 이 서버의 경로인 경우 포스터 파일을 직접 제공합니다.
+	// 다른 용도의 파일이 노출되지 않도록 posters 하위 디렉터리만 제공합니다.
+	if app.config.storage.backend == "local" && strings.HasPrefix(app.config.storage.localURL, "/") {
+		router.ServeFiles(app.config.storage.localURL+"/posters/*filepath", http.Dir(filepath.Join(app.config.storage.localDir, "posters")))
+	}
+
 	// Use metrics
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
+	return app.metrics(app.compress(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))))
 }
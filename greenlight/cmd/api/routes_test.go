@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// httprouter는 충돌하는 경로를 등록하면 패닉을 일으키므로 서버가 시작되기 전에 발견할 수 있도록
+// 모든 경로가 문제없이 등록되는지 확인합니다.
+func TestRoutes(t *testing.T) {
+	app := newTestApplication("production")
+
+	origins, err := newOriginList(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.corsOrigins = origins
+
+	defer func() {
+		if err := recover(); err != nil {
+			t.Fatalf("routes() panicked: %v", err)
+		}
+	}()
+
+	app.routes()
+}
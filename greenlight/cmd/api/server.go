@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -19,6 +21,12 @@ func (app *application) serve() error {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	// 모든 요청 컨텍스트의 부모 컨텍스트입니다. 종료 기한이 지나도 끝나지 않은 요청이 있으면
+	// 이를 취소하여 진행 중인 데이터베이스 쿼리를 중단합니다.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv.BaseContext = func(net.Listener) context.Context { return baseCtx }
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -29,25 +37,48 @@ func (app *application) serve() error {
 		app.logger.PrintInfo("shutting down server", map[string]string{
 			"signal": s.String(),
 		})
-		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+
+		// 준비 상태 검사가 즉시 503을 반환하도록 하여 로드 밸런서가 새 요청을 보내지 않게 합니다.
+		app.draining.Store(true)
+
+		// 로드 밸런서가 다음 준비 상태 검사에서 503을 확인하기 전까지는 계속 새 요청이 올 수 있으므로
+		// 곧바로 리스너를 닫지 않고 잠시 동안 요청을 계속 처리합니다.
+		if app.config.shutdownDrainDelay > 0 {
+			app.logger.PrintInfo("draining connections", map[string]string{
+				"delay": app.config.shutdownDrainDelay.String(),
+			})
+			time.Sleep(app.config.shutdownDrainDelay)
+		}
+
+		// 요청과 백그라운드 작업은 같은 기한을 공유합니다.
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
 		defer cancel()
 
-		// 이전처럼 서버에서 Shutdown()을 호출하지만, 이제 오류를 반환하는 경우에만
-		// shutdownError 채널에 전송합니다.
 		err := srv.Shutdown(ctx)
 		if err != nil {
-			shutdownError <- err
+			cancelRequests()
 		}
 
 		// 백그라운드 고루틴이 작업을 완료하기를 기다리고 있다는 메시지를 기록합니다.
 		app.logger.PrintInfo("completing background tasks", map[string]string{
-			"addr": srv.Addr,
+			"addr":    srv.Addr,
+			"running": strconv.Itoa(len(app.tasks.Running())),
 		})
-		// Wait()를 호출하여 WaitGroup 카운터가 0이 될 때까지 차단하고, 백그라운드 고루틴이
-		// 완료될 때까지 차단합니다. 그런 다음 종료가 문제 없이 완료되었음을 나타내기 위해
-		// shutdownError 채널에 nil을 반환합니다.
-		app.wg.Wait()
-		shutdownError <- nil
+
+		// 기한 안에 끝나지 않은 작업은 컨텍스트가 취소되고, 어떤 작업이 중단되었는지 기록합니다.
+		abandoned := app.tasks.Shutdown(ctx)
+		for _, task := range abandoned {
+			app.logger.PrintError(errors.New("background task abandoned"), map[string]string{
+				"task":        task.Name,
+				"running_for": time.Since(task.Started).Round(time.Millisecond).String(),
+			})
+		}
+
+		if err == nil && len(abandoned) > 0 {
+			err = fmt.Errorf("%d background tasks did not finish before the shutdown deadline", len(abandoned))
+		}
+
+		shutdownError <- err
 	}()
 
 	app.logger.PrintInfo("starting server", map[string]string{
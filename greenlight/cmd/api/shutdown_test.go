@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"greenlight.wook.net/internal/data"
+	"greenlight.wook.net/internal/jsonlog"
+	"greenlight.wook.net/internal/mailer"
+	"greenlight.wook.net/internal/migrate"
+)
+
+// blockingConnector는 모든 쿼리를 컨텍스트가 완료될 때까지 멈춰 두는 가짜 데이터베이스 드라이버입니다.
+// 쿼리가 시작되면 started에, 쿼리가 중단된 이유는 stopped에 보냅니다.
+type blockingConnector struct {
+	started chan struct{}
+	stopped chan error
+}
+
+func (c *blockingConnector) Connect(context.Context) (driver.Conn, error) {
+	return blockingConn{c}, nil
+}
+func (c *blockingConnector) Driver() driver.Driver { return nil }
+
+type blockingConn struct {
+	c *blockingConnector
+}
+
+func (conn blockingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (conn blockingConn) Close() error { return nil }
+func (conn blockingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (conn blockingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.c.started <- struct{}{}
+	<-ctx.Done()
+	conn.c.stopped <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestClientDisconnectCancelsQuery(t *testing.T) {
+	connector := &blockingConnector{started: make(chan struct{}, 1), stopped: make(chan error, 1)}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	app := newTestApplication("production")
+	app.models = data.NewModels(db)
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.showMovieHandler)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/movies/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		resp, err := ts.Client().Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	select {
+	case <-connector.started:
+	case <-time.After(time.Second):
+		t.Fatal("query was never started")
+	}
+
+	// 클라이언트가 연결을 끊으면 모델의 3초 제한 시간보다 훨씬 빨리 쿼리가 취소되어야 합니다.
+	cancel()
+
+	select {
+	case err := <-connector.stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("query stopped with %v; want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("query was not cancelled after the client disconnected")
+	}
+}
+
+func TestTaskTrackerShutdown(t *testing.T) {
+	app := newTestApplication("production")
+
+	cancelled := make(chan struct{})
+
+	app.background("quick task", func(ctx context.Context) {})
+	app.background("stuck task", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	abandoned := app.tasks.Shutdown(ctx)
+	if len(abandoned) != 1 || abandoned[0].Name != "stuck task" {
+		t.Fatalf("abandoned = %+v; want only the stuck task", abandoned)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("abandoned task's context was not cancelled")
+	}
+}
+
+func TestTaskTrackerShutdownCompleted(t *testing.T) {
+	app := newTestApplication("production")
+
+	release := make(chan struct{})
+	app.background("email", func(ctx context.Context) {
+		<-release
+	})
+
+	if running := app.tasks.Running(); len(running) != 1 || running[0].Name != "email" {
+		t.Fatalf("Running() = %+v; want the email task", running)
+	}
+
+	close(release)
+
+	if abandoned := app.tasks.Shutdown(context.Background()); abandoned != nil {
+		t.Errorf("abandoned = %+v; want nil", abandoned)
+	}
+}
+
+// readyConnector는 준비 상태 검사에 필요한 쿼리에만 응답하는 가짜 데이터베이스 드라이버입니다.
+// 마이그레이션 테이블이 없다고 답하므로 마이그레이션 검사는 버전 0으로 통과합니다.
+type readyConnector struct{}
+
+func (readyConnector) Connect(context.Context) (driver.Conn, error) { return readyConn{}, nil }
+func (readyConnector) Driver() driver.Driver                        { return nil }
+
+type readyConn struct{}
+
+func (readyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (readyConn) Close() error { return nil }
+func (readyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (readyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &boolRows{}, nil
+}
+
+type boolRows struct {
+	done bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"exists"} }
+func (r *boolRows) Close() error      { return nil }
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = false
+	return nil
+}
+
+func TestHealthWhileDraining(t *testing.T) {
+	db := sql.OpenDB(readyConnector{})
+	defer db.Close()
+
+	migrator, err := migrate.New(db, fstest.MapFS{}, jsonlog.New(io.Discard, jsonlog.LevelOff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication("production")
+	app.db = db
+	app.migrator = migrator
+	// SMTP 검사는 critical이 아니므로 연결할 수 없는 주소를 사용해도 준비 상태에는 영향이 없습니다.
+	app.mailer = mailer.New("127.0.0.1", 1, "", "", "")
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/v1/healthz/live", app.livenessHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthz/ready", app.readinessHandler)
+
+	get := func(path string) (int, string) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var body struct {
+			Status string `json:"status"`
+		}
+		err := json.NewDecoder(rr.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return rr.Code, body.Status
+	}
+
+	tests := []struct {
+		draining   bool
+		path       string
+		wantCode   int
+		wantStatus string
+	}{
+		{false, "/v1/healthz/live", http.StatusOK, "alive"},
+		{false, "/v1/healthz/ready", http.StatusOK, "ready"},
+		{true, "/v1/healthz/live", http.StatusOK, "alive"},
+		{true, "/v1/healthz/ready", http.StatusServiceUnavailable, "draining"},
+	}
+
+	for _, tt := range tests {
+		app.draining.Store(tt.draining)
+
+		code, status := get(tt.path)
+		if code != tt.wantCode || status != tt.wantStatus {
+			t.Errorf("draining=%t %s: got %d %q; want %d %q", tt.draining, tt.path, code, status, tt.wantCode, tt.wantStatus)
+		}
+	}
+}
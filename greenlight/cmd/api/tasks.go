@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// backgroundTask는 실행 중인 백그라운드 작업 하나의 정보입니다.
+type backgroundTask struct {
+	Name    string
+	Started time.Time
+}
+
+// taskTracker는 app.background()로 시작한 고루틴을 이름과 함께 추적합니다. 종료 시 Shutdown()은
+// 기한까지 작업이 끝나기를 기다리고, 기한이 지나면 작업의 컨텍스트를 취소한 뒤 끝나지 않은 작업을 반환합니다.
+type taskTracker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	nextID  uint64
+	running map[uint64]backgroundTask
+}
+
+func newTaskTracker() *taskTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &taskTracker{
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[uint64]backgroundTask),
+	}
+}
+
+// start()는 새 작업을 등록하고 작업이 사용할 컨텍스트와 작업이 끝났을 때 호출할 함수를 반환합니다.
+func (t *taskTracker) start(name string) (context.Context, func()) {
+	t.mu.Lock()
+	t.nextID++
+	id := t.nextID
+	t.running[id] = backgroundTask{Name: name, Started: time.Now()}
+	t.mu.Unlock()
+
+	t.wg.Add(1)
+
+	return t.ctx, func() {
+		t.mu.Lock()
+		delete(t.running, id)
+		t.mu.Unlock()
+
+		t.wg.Done()
+	}
+}
+
+// Running()은 실행 중인 작업을 시작한 순서대로 반환합니다.
+func (t *taskTracker) Running() []backgroundTask {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tasks := make([]backgroundTask, 0, len(t.running))
+	for _, task := range t.running {
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Started.Before(tasks[j].Started)
+	})
+
+	return tasks
+}
+
+// Shutdown()은 모든 작업이 끝나거나 ctx가 완료될 때까지 기다립니다. 기한이 지나면 작업의 컨텍스트를
+// 취소하고 그 시점에 끝나지 않은 작업을 반환합니다. 모든 작업이 끝났다면 nil을 반환합니다.
+func (t *taskTracker) Shutdown(ctx context.Context) []backgroundTask {
+	done := make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.cancel()
+		return nil
+	case <-ctx.Done():
+		abandoned := t.Running()
+		t.cancel()
+		return abandoned
+	}
+}
+
+// background()는 fn을 이름이 있는 백그라운드 작업으로 실행합니다. fn에 전달되는 컨텍스트는 요청과 무관하며
+// 서버 종료 기한이 지나면 취소되므로, 데이터베이스 작업은 이 컨텍스트를 사용해야 합니다.
+func (app *application) background(name string, fn func(ctx context.Context)) {
+	ctx, done := app.tasks.start(name)
+
+	go func() {
+		defer done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), map[string]string{"task": name})
+			}
+		}()
+
+		fn(ctx)
+	}()
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	"greenlight.wook.net/internal/data"
 	"greenlight.wook.net/internal/validator"
@@ -29,13 +28,27 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	account, ip, err := loginSubjects(r, input.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// 계정이나 IP 주소가 차단된 동안에는 비밀번호를 확인하지 않고 바로 거부합니다.
+	attempt := app.beginLoginAttempt(w, r, account, ip)
+	if attempt == nil {
+		return
+	}
+
 	// 이메일 주소를 기준으로 사용자 레코드를 조회합니다. 일치하는 사용자를
 	// 찾지 못하면 app.invalidCredentialsResponse() 헬퍼를 호출하여 클라이언트에 401
 	// 권한 없음 응답을 보냅니다(이 헬퍼는 곧 생성할 예정입니다).
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			// 존재하지 않는 계정에 대한 시도도 똑같이 기록하여 응답만으로 계정 존재 여부를 알 수 없게 합니다.
+			app.loginFailed(attempt, nil)
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -50,20 +63,41 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.loginFailed(attempt, user)
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	// 그렇지 않으면 비밀번호가 맞으면 만료 시간이 24시간이고 범위가 '인증'인 새 토큰을 생성합니다.
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	// 로그인에 성공하면 미리 기록한 실패를 되돌리고 계정의 실패 기록을 지웁니다. IP 주소 기록은 공격자가
+	// 자신의 계정으로 로그인하여 초기화할 수 없도록 그대로 두고 시간이 지나면 만료되게 합니다.
+	err = app.models.LoginFailures.Forgive(r.Context(), attempt)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	//  토큰을 JSON으로 인코딩하고 201 생성됨 상태 코드와 함께 응답으로 전송합니다.
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
-	if err != nil {
+	// TOTP를 사용하는 계정이라면 인증 토큰 대신 짧은 유효 기간의 mfa-pending 토큰을 발급합니다.
+	// 클라이언트는 이 토큰과 인증 앱의 코드를 POST /v1/tokens/mfa로 보내 인증 토큰을 받아야 합니다.
+	mfa, err := app.models.MFA.Get(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
+
+	if mfa != nil && mfa.Enabled {
+		token, err := app.models.Tokens.New(r.Context(), user.ID, mfaPendingTTL, data.ScopeMFAPending)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, r, http.StatusOK, envelope{"mfa_required": true, "mfa_token": token}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// 그렇지 않으면 비밀번호가 맞으면 만료 시간이 24시간이고 범위가 '인증'인 새 토큰을 생성합니다.
+	app.issueAuthenticationToken(w, r, user)
 }
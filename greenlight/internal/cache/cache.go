@@ -0,0 +1,22 @@
+// cache 패키지는 바이트 값을 저장하는 단순한 키-값 캐시 인터페이스와
+// 메모리 기반 LRU, Redis 호환 서버를 사용하는 두 가지 구현을 제공합니다.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache는 캐시 백엔드가 구현해야 하는 메서드입니다. 캐시는 언제든 값을 잃을 수 있으므로
+// 호출자는 Get()의 결과가 없거나 오류가 발생하면 원본 저장소에서 값을 읽어야 합니다.
+type Cache interface {
+	// Get()은 키에 해당하는 값과 존재 여부를 반환합니다.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set()은 ttl 동안 유효한 값을 저장합니다. ttl이 0이면 만료되지 않습니다.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Incr()는 정수 카운터를 1 증가시키고 증가된 값을 반환합니다. 키가 없으면 0에서 시작합니다.
+	Incr(ctx context.Context, key string) (int64, error)
+	// Counter()는 Incr()로 관리하는 카운터의 현재 값을 반환하며, 키가 없으면 0을 반환합니다.
+	Counter(ctx context.Context, key string) (int64, error)
+}
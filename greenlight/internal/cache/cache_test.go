@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+
+	// "a"를 조회하여 가장 최근에 사용한 항목으로 만들면 다음 Set()에서 "b"가 제거됩니다.
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal(`"a" missing`)
+	}
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error(`"b" should have been evicted`)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%q should still be cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d; want 2", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "k", []byte("v"), time.Minute)
+
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := c.Get(ctx, "k"); !ok {
+		t.Error("value expired too early")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("value should have expired")
+	}
+}
+
+func TestLRUCountersSurviveEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(1)
+
+	c.Incr(ctx, "version")
+	c.Incr(ctx, "version")
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+
+	if n, _ := c.Counter(ctx, "version"); n != 2 {
+		t.Errorf("Counter() = %d; want 2", n)
+	}
+}
+
+// fakeRedis는 테스트에 필요한 명령만 지원하는 메모리 기반 RESP 서버입니다.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+	ln     net.Listener
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeRedis{values: make(map[string]string), ln: ln}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, password)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn, password string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authed := password == ""
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		s.mu.Lock()
+		switch cmd {
+		case "AUTH":
+			if args[1] == password {
+				authed = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "GET":
+			v, ok := s.values[args[1]]
+			if !ok {
+				io.WriteString(conn, "$-1\r\n")
+			} else {
+				io.WriteString(conn, "$"+strconv.Itoa(len(v))+"\r\n"+v+"\r\n")
+			}
+		case "SET":
+			s.values[args[1]] = args[2]
+			io.WriteString(conn, "+OK\r\n")
+		case "DEL":
+			for _, key := range args[1:] {
+				delete(s.values, key)
+			}
+			io.WriteString(conn, ":"+strconv.Itoa(len(args)-1)+"\r\n")
+		case "INCR":
+			n, _ := strconv.Atoi(s.values[args[1]])
+			n++
+			s.values[args[1]] = strconv.Itoa(n)
+			io.WriteString(conn, ":"+strconv.Itoa(n)+"\r\n")
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, size+2)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	srv := newFakeRedis(t, "secret")
+
+	c, err := NewRedis(RedisConfig{Addr: srv.ln.Addr().String(), Password: "secret", Prefix: "greenlight:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := c.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Get(missing) = %t, %v; want false, nil", ok, err)
+	}
+
+	value := []byte("binary\r\n\x00value")
+	if err := c.Set(ctx, "movie:1", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := c.Get(ctx, "movie:1")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Errorf("Get(movie:1) = %q, %t, %v; want %q", got, ok, err, value)
+	}
+
+	srv.mu.Lock()
+	_, ok = srv.values["greenlight:movie:1"]
+	srv.mu.Unlock()
+	if !ok {
+		t.Error("key prefix was not applied")
+	}
+
+	if err := c.Delete(ctx, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "movie:1"); ok {
+		t.Error("value still present after Delete()")
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		n, err := c.Incr(ctx, "version")
+		if err != nil || n != want {
+			t.Errorf("Incr() = %d, %v; want %d", n, err, want)
+		}
+	}
+	if n, err := c.Counter(ctx, "version"); err != nil || n != 3 {
+		t.Errorf("Counter() = %d, %v; want 3", n, err)
+	}
+}
+
+func TestRedisAuthFailure(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+
+	c, err := NewRedis(RedisConfig{Addr: srv.ln.Addr().String(), Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Ping() error = %v; want WRONGPASS", err)
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU는 최대 capacity개의 항목을 보관하고 가장 오래 사용되지 않은 항목부터 제거하는 메모리 캐시입니다.
+// 프로세스마다 따로 유지되므로 여러 인스턴스를 실행할 때는 Redis 백엔드를 사용해야 무효화가 공유됩니다.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	// 카운터는 제거되면 이전 값으로 되돌아가 오래된 항목이 다시 유효해질 수 있으므로 LRU 목록과 따로 관리합니다.
+	counters map[string]int64
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		counters: make(map[string]int64),
+		now:      time.Now,
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.removeElement(el)
+		return nil, false, nil
+	}
+
+	c.ll.MoveToFront(el)
+
+	return entry.value, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		c.ll.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expires: expires})
+
+	for c.capacity > 0 && c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+		delete(c.counters, key)
+	}
+
+	return nil
+}
+
+func (c *LRU) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters[key]++
+
+	return c.counters[key], nil
+}
+
+func (c *LRU) Counter(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counters[key], nil
+}
+
+// Len()은 현재 보관 중인 항목 수를 반환합니다. 만료되었지만 아직 조회되지 않은 항목도 포함됩니다.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *LRU) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruEntry).key)
+}
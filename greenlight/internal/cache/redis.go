@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig는 Redis 호환 서버(Redis, Valkey, KeyDB 등)에 연결하기 위한 설정입니다.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize는 유지할 유휴 연결의 최대 수입니다.
+	PoolSize int
+	// Prefix는 같은 서버를 다른 애플리케이션과 공유할 때 키 충돌을 막기 위해 모든 키 앞에 붙입니다.
+	Prefix string
+}
+
+// Redis는 RESP 프로토콜로 GET, SET, DEL, INCR 명령만 사용하는 최소한의 클라이언트입니다.
+type Redis struct {
+	cfg  RedisConfig
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError는 서버가 반환한 오류 응답입니다. 연결 자체는 계속 사용할 수 있습니다.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+var errRedisNil = errors.New("redis: nil")
+
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("redis: address must be provided")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+
+	return &Redis{cfg: cfg, pool: make(chan *redisConn, cfg.PoolSize)}, nil
+}
+
+// Ping()은 서버에 연결할 수 있는지 확인합니다.
+func (c *Redis) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "PING")
+	return err
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", c.cfg.Prefix+key)
+	if err != nil {
+		if errors.Is(err, errRedisNil) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %T to GET", reply)
+	}
+
+	return b, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", c.cfg.Prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := []any{"DEL"}
+	for _, key := range keys {
+		args = append(args, c.cfg.Prefix+key)
+	}
+
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+func (c *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := c.do(ctx, "INCR", c.cfg.Prefix+key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %T to INCR", reply)
+	}
+
+	return n, nil
+}
+
+func (c *Redis) Counter(ctx context.Context, key string) (int64, error) {
+	b, ok, err := c.Get(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(b), 10, 64)
+}
+
+// do()는 풀에서 연결을 가져와 명령 하나를 실행합니다. 네트워크 오류가 발생한 연결은
+// 응답 스트림의 위치를 알 수 없으므로 풀에 돌려놓지 않고 닫습니다.
+func (c *Redis) do(ctx context.Context, args ...any) (any, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(3 * time.Second)
+	}
+	conn.conn.SetDeadline(deadline)
+
+	reply, err := conn.roundTrip(args...)
+
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) && !errors.Is(err, errRedisNil) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	c.put(conn)
+
+	return reply, err
+}
+
+func (c *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	var d net.Dialer
+
+	nc, err := d.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: nc, r: bufio.NewReader(nc)}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+
+	if c.cfg.Password != "" {
+		_, err = conn.roundTrip("AUTH", c.cfg.Password)
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	if c.cfg.DB != 0 {
+		_, err = conn.roundTrip("SELECT", strconv.Itoa(c.cfg.DB))
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Redis) put(conn *redisConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// Close()는 풀에 있는 모든 유휴 연결을 닫습니다.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// roundTrip()은 명령을 RESP 배열로 보내고 응답 하나를 읽습니다.
+func (rc *redisConn) roundTrip(args ...any) (any, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		default:
+			return nil, fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(b)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, b...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	_, err := rc.conn.Write(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return rc.readReply()
+}
+
+func (rc *redisConn) readReply() (any, error) {
+	line, err := rc.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+
+		b := make([]byte, n+2)
+		_, err = io.ReadFull(rc.r, b)
+		if err != nil {
+			return nil, err
+		}
+
+		return b[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply type %q", line[0])
+	}
+}
+
+func (rc *redisConn) readLine() (string, error) {
+	line, err := rc.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("redis: malformed reply")
+	}
+
+	return line[:len(line)-2], nil
+}
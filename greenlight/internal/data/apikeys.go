@@ -0,0 +1,224 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"greenlight.wook.net/internal/validator"
+)
+
+// API 키 일반 텍스트는 32바이트 난수를 패딩 없는 base32로 인코딩한 52자 문자열입니다.
+const apiKeyPlaintextLength = 52
+
+// ApiKey는 배치 작업 같은 머신 클라이언트를 위한 장기 자격 증명입니다. 인증 토큰과 마찬가지로
+// 데이터베이스에는 SHA-256 해시만 저장하며, 일반 텍스트는 생성 직후 한 번만 클라이언트에 전달됩니다.
+type ApiKey struct {
+	ID          int64       `json:"id"`
+	Plaintext   string      `json:"key,omitempty"`
+	Hash        []byte      `json:"-"`
+	UserID      int64       `json:"-"`
+	Name        string      `json:"name"`
+	Permissions Permissions `json:"permissions"`
+	Expiry      *time.Time  `json:"expiry,omitempty"`
+	LastUsedAt  *time.Time  `json:"last_used_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func generateApiKey(userID int64, name string, permissions Permissions, expiry *time.Time) (*ApiKey, error) {
+	key := &ApiKey{
+		UserID:      userID,
+		Name:        name,
+		Permissions: permissions,
+		Expiry:      expiry,
+	}
+
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(key.Plaintext))
+	key.Hash = hash[:]
+
+	return key, nil
+}
+
+func ValidateApiKeyPlaintext(v *validator.Validator, plaintext string) {
+	v.Check(plaintext != "", "key", "must be provided")
+	v.Check(len(plaintext) == apiKeyPlaintextLength, "key", "must be 52 bytes long")
+}
+
+func ValidateApiKey(v *validator.Validator, key *ApiKey) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Permissions) >= 1, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(key.Permissions), "permissions", "must not contain duplicate values")
+
+	if key.Expiry != nil {
+		v.Check(key.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+}
+
+type ApiKeyModel struct {
+	DB *sql.DB
+}
+
+// New()는 새 API 키를 생성하여 api_keys 테이블에 삽입하는 바로 가기입니다.
+func (m ApiKeyModel) New(ctx context.Context, userID int64, name string, permissions Permissions, expiry *time.Time) (*ApiKey, error) {
+	key, err := generateApiKey(userID, name, permissions, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(ctx, key)
+	return key, err
+}
+
+func (m ApiKeyModel) Insert(ctx context.Context, key *ApiKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, hash, permissions, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	args := []any{key.UserID, key.Name, key.Hash, pq.Array([]string(key.Permissions)), key.Expiry}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetAllForUser()는 사용자의 모든 API 키를 만료 여부와 관계없이 생성 순서대로 반환합니다.
+func (m ApiKeyModel) GetAllForUser(ctx context.Context, userID int64) ([]*ApiKey, error) {
+	query := `
+		SELECT id, user_id, name, permissions, expiry, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*ApiKey{}
+
+	for rows.Next() {
+		var key ApiKey
+
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			pq.Array((*[]string)(&key.Permissions)),
+			&key.Expiry,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Delete()는 특정 사용자의 API 키를 폐기합니다. 다른 사용자의 키 ID를 지정하면 ErrRecordNotFound를 반환합니다.
+func (m ApiKeyModel) Delete(ctx context.Context, id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM api_keys
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetForKey()는 만료되지 않은 API 키와 그 소유자를 조회하고, 같은 쿼리에서 키의 마지막 사용 시각을 갱신합니다.
+func (m ApiKeyModel) GetForKey(ctx context.Context, plaintext string) (*User, *ApiKey, error) {
+	keyHash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+		WITH key AS (
+			UPDATE api_keys
+			SET last_used_at = $2
+			WHERE hash = $1 AND (expiry IS NULL OR expiry > $2)
+			RETURNING id, user_id, name, permissions, expiry, last_used_at, created_at
+		)
+		SELECT key.id, key.user_id, key.name, key.permissions, key.expiry, key.last_used_at, key.created_at,
+			users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		FROM key
+		INNER JOIN users ON users.id = key.user_id`
+
+	var key ApiKey
+	var user User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, keyHash[:], time.Now()).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		pq.Array((*[]string)(&key.Permissions)),
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return &user, &key, nil
+}
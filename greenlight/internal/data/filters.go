@@ -70,10 +70,10 @@ func (f Filters) offset() int {
 }
 
 func ValidateFilter(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "0 보다 커야만 합니다")
-	v.Check(f.Page <= 10_000_000, "page", "최대 1000만 까지 요청할 수 있습니다")
-	v.Check(f.PageSize > 0, "page_size", "0 보다 커야만 합니다")
-	v.Check(f.PageSize <= 100, "page_size", "최대 100 까지 요청할 수 있습니다")
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "잘못된 sort 값 입니다")
+	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
 }
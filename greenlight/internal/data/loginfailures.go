@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 로그인 실패는 계정(소문자 이메일 주소)과 클라이언트 IP 주소 기준으로 따로 집계합니다.
+// 계정 기준 집계는 존재하지 않는 이메일 주소에도 적용되므로 잠금 응답으로 계정 존재 여부를 알 수 없습니다.
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+type LoginFailure struct {
+	Scope        string
+	Subject      string
+	Failures     int
+	LastFailedAt time.Time
+	BlockedUntil *time.Time
+	Locked       bool
+}
+
+// Blocked()는 주어진 시각에 아직 로그인 시도가 차단되어 있는지와 남은 시간을 반환합니다.
+func (f *LoginFailure) Blocked(now time.Time) (bool, time.Duration) {
+	if f.BlockedUntil == nil || !f.BlockedUntil.After(now) {
+		return false, 0
+	}
+	return true, f.BlockedUntil.Sub(now)
+}
+
+// LoginFailureModel은 재시작 후에도 잠금 상태가 유지되도록 실패 기록을 데이터베이스에 저장합니다.
+type LoginFailureModel struct {
+	DB *sql.DB
+}
+
+// LoginAttempt는 Attempt()가 비밀번호를 확인하기 전에 미리 실패로 기록한 로그인 시도입니다.
+type LoginAttempt struct {
+	// Blocked는 시도가 거부되었을 때 이를 차단한 기록입니다. 이 경우 실패 횟수는 늘어나지 않습니다.
+	Blocked *LoginFailure
+	Account *LoginFailure
+	IP      *LoginFailure
+}
+
+// LoginPenalty는 scope의 실패 횟수가 failures가 되었을 때 다음 시도까지 차단할 시간과 계정 잠금 여부를 정합니다.
+type LoginPenalty func(scope string, failures int) (time.Duration, bool)
+
+// Attempt()는 계정과 IP 주소의 기록을 잠근 채로 차단 여부를 확인하고, 차단되어 있지 않다면
+// 이번 시도를 실패로 세고 penalty에 따라 다음 차단 시각을 설정합니다. 확인과 기록이 하나의
+// 트랜잭션에서 일어나므로 동시에 들어온 요청들도 차례대로 집계되어 차단을 우회할 수 없습니다.
+// 로그인이 성공하면 Forgive()로 이번 시도를 되돌려야 합니다.
+func (m LoginFailureModel) Attempt(ctx context.Context, account, ip string, window time.Duration, penalty LoginPenalty) (*LoginAttempt, error) {
+	now := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 교착 상태를 피하기 위해 모든 요청이 계정, IP 주소 순서로 행을 잠급니다.
+	subjects := []struct{ scope, subject string }{
+		{LoginScopeAccount, account},
+		{LoginScopeIP, ip},
+	}
+
+	failures := make([]*LoginFailure, len(subjects))
+	for i, s := range subjects {
+		failures[i], err = lockLoginFailure(ctx, tx, s.scope, s.subject, now)
+		if err != nil {
+			return nil, err
+		}
+
+		// 차단된 시도는 집계하지 않으므로 트랜잭션을 롤백하여 방금 만든 빈 기록도 지웁니다.
+		if blocked, _ := failures[i].Blocked(now); blocked {
+			return &LoginAttempt{Blocked: failures[i]}, nil
+		}
+	}
+
+	query := `
+		UPDATE login_failures
+		SET failures = $3, last_failed_at = $4, blocked_until = $5, locked = $6
+		WHERE scope = $1 AND subject = $2`
+
+	for _, f := range failures {
+		if f.LastFailedAt.Before(now.Add(-window)) {
+			f.Failures = 0
+		}
+		f.Failures++
+		f.LastFailedAt = now
+
+		delay, locked := penalty(f.Scope, f.Failures)
+		f.BlockedUntil, f.Locked = nil, false
+		if delay > 0 {
+			until := now.Add(delay)
+			f.BlockedUntil, f.Locked = &until, locked
+		}
+
+		_, err = tx.ExecContext(ctx, query, f.Scope, f.Subject, f.Failures, f.LastFailedAt, f.BlockedUntil, f.Locked)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginAttempt{Account: failures[0], IP: failures[1]}, nil
+}
+
+// lockLoginFailure()는 기록이 없으면 실패 횟수 0으로 만든 뒤 트랜잭션이 끝날 때까지 행을 잠급니다.
+func lockLoginFailure(ctx context.Context, tx *sql.Tx, scope, subject string, now time.Time) (*LoginFailure, error) {
+	query := `
+		INSERT INTO login_failures (scope, subject, failures, last_failed_at)
+		VALUES ($1, $2, 0, $3)
+		ON CONFLICT (scope, subject) DO NOTHING`
+
+	_, err := tx.ExecContext(ctx, query, scope, subject, now)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT scope, subject, failures, last_failed_at, blocked_until, locked
+		FROM login_failures
+		WHERE scope = $1 AND subject = $2
+		FOR UPDATE`
+
+	var failure LoginFailure
+
+	err = tx.QueryRowContext(ctx, query, scope, subject).Scan(
+		&failure.Scope,
+		&failure.Subject,
+		&failure.Failures,
+		&failure.LastFailedAt,
+		&failure.BlockedUntil,
+		&failure.Locked,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &failure, nil
+}
+
+// Forgive()는 성공한 로그인에 대해 Attempt()가 미리 기록한 실패를 되돌립니다. 계정 기록은 삭제하고,
+// IP 주소 기록은 공격자가 자신의 계정으로 로그인하여 초기화할 수 없도록 이번 시도만큼만 줄이고
+// 이번 시도가 설정한 차단을 해제합니다.
+func (m LoginFailureModel) Forgive(ctx context.Context, attempt *LoginAttempt) error {
+	err := m.Reset(ctx, LoginScopeAccount, attempt.Account.Subject)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE login_failures
+		SET failures = GREATEST(failures - 1, 0),
+			blocked_until = CASE WHEN blocked_until = $3 THEN NULL ELSE blocked_until END
+		WHERE scope = $1 AND subject = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, LoginScopeIP, attempt.IP.Subject, attempt.IP.BlockedUntil)
+	return err
+}
+
+// Reset()은 로그인 성공이나 관리자의 잠금 해제 후 실패 기록을 삭제합니다.
+func (m LoginFailureModel) Reset(ctx context.Context, scope, subject string) error {
+	query := `
+		DELETE FROM login_failures
+		WHERE scope = $1 AND subject = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, subject)
+	return err
+}
@@ -0,0 +1,251 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"greenlight.wook.net/internal/validator"
+)
+
+// 복구 코드는 인증 앱을 잃어버렸을 때 한 번씩 사용할 수 있는 코드로, 활성화할 때 이 개수만큼 발급합니다.
+const RecoveryCodeCount = 10
+
+// MFA는 사용자의 TOTP 설정입니다. 등록을 시작하면 Enabled가 false인 상태로 저장되고, 사용자가
+// 인증 앱의 코드로 확인해야 활성화됩니다. LastCounter는 이미 사용한 코드의 재사용을 막기 위한 값입니다.
+type MFA struct {
+	UserID      int64
+	Secret      string
+	Enabled     bool
+	LastCounter int64
+	CreatedAt   time.Time
+}
+
+type MFAModel struct {
+	DB *sql.DB
+}
+
+func (m MFAModel) Get(ctx context.Context, userID int64) (*MFA, error) {
+	query := `
+		SELECT user_id, secret, enabled, last_counter, created_at
+		FROM user_mfa
+		WHERE user_id = $1`
+
+	var mfa MFA
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.Enabled,
+		&mfa.LastCounter,
+		&mfa.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &mfa, nil
+}
+
+// Enroll()은 새 비밀 키로 비활성 상태의 설정을 now 시각에 저장합니다. 이미 활성화된 설정은 덮어쓰지 않으며,
+// 이 경우 ErrEditConflict를 반환합니다.
+func (m MFAModel) Enroll(ctx context.Context, userID int64, secret string, now time.Time) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = $2, last_counter = 0, created_at = $3
+		WHERE user_mfa.enabled = false`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret, now)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// UseCounter()는 counter가 마지막으로 사용한 주기보다 클 때만 기록하고 활성화합니다.
+// 같은 코드가 두 번 사용되면 두 번째 호출은 ErrEditConflict를 반환합니다.
+func (m MFAModel) UseCounter(ctx context.Context, userID int64, counter int64) error {
+	query := `
+		UPDATE user_mfa
+		SET last_counter = $2, enabled = true
+		WHERE user_id = $1 AND last_counter < $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// Delete()는 TOTP 설정과 복구 코드를 함께 삭제합니다.
+func (m MFAModel) Delete(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewRecoveryCodes()는 기존 복구 코드를 모두 무효화하고 새 코드를 발급하여 일반 텍스트로 반환합니다.
+// 데이터베이스에는 토큰과 마찬가지로 SHA-256 해시만 저장합니다.
+func (m MFAModel) NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (hash, user_id) VALUES ($1, $2)`, hash[:], userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode()는 사용하지 않은 복구 코드와 일치하면 사용 처리하고 true를 반환합니다.
+func (m MFAModel) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE hash = $1 AND user_id = $2 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hash[:], userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RecoveryCodesRemaining()은 아직 사용하지 않은 복구 코드의 개수를 반환합니다.
+func (m MFAModel) RecoveryCodesRemaining(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var remaining int
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&remaining)
+	return remaining, err
+}
+
+// 복구 코드는 10바이트 난수를 base32로 인코딩한 16자를 읽기 쉽도록 "xxxx-xxxx-xxxx-xxxx" 형식으로 나눕니다.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// 사용자가 하이픈이나 대소문자를 다르게 입력해도 일치하도록 정규화합니다.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+func ValidateTOTPCode(v *validator.Validator, code string) {
+	v.Check(code != "", "code", "must be provided")
+	v.Check(len(code) == 6, "code", "must be 6 digits long")
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,31 +11,46 @@ var (
 	ErrEditConflict   = errors.New("edit confilct")
 )
 
+// MovieStore는 movie 데이터에 접근하는 메서드입니다. MovieModel이 데이터베이스를 직접 사용하고,
+// CachedMovieModel은 다른 MovieStore를 감싸 읽기 결과를 캐시합니다.
+type MovieStore interface {
+	Insert(ctx context.Context, movie *Movie) error
+	Get(ctx context.Context, id int64) (*Movie, error)
+	Update(ctx context.Context, movie *Movie, editorID int64) error
+	Delete(ctx context.Context, id int64) error
+	GetAll(ctx context.Context, title string, genres []string, filters Filters) ([]*Movie, Metadata, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*Movie, error)
+}
+
 type Models struct {
-	Movies interface {
-		Insert(movie *Movie) error
-		Get(id int64) (*Movie, error)
-		Update(movie *Movie) error
-		Delete(id int64) error
-		GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error)
-	}
-	Permissions PermissionModel
-	Tokens      TokenModel
+	Movies        MovieStore
+	ApiKeys       ApiKeyModel
+	LoginFailures LoginFailureModel
+	MFA           MFAModel
+	MovieVersions MovieVersionModel
+	Permissions   PermissionModel
+	Tokens        TokenModel
 
 	Users interface {
-		Insert(user *User) error
-		GetByEmail(email string) (*User, error)
-		Update(user *User) error
-		GetForToken(tokenScope, tokenPlaintext string) (*User, error)
+		Insert(ctx context.Context, user *User) error
+		Get(ctx context.Context, id int64) (*User, error)
+		GetByEmail(ctx context.Context, email string) (*User, error)
+		Update(ctx context.Context, user *User) error
+		GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+		Delete(ctx context.Context, id int64) error
 	}
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Users:       UserModel{DB: db},
+		Movies:        MovieModel{DB: db},
+		ApiKeys:       ApiKeyModel{DB: db},
+		LoginFailures: LoginFailureModel{DB: db},
+		MFA:           MFAModel{DB: db},
+		MovieVersions: MovieVersionModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Users:         UserModel{DB: db},
 	}
 }
 
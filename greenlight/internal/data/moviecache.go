@@ -0,0 +1,215 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"greenlight.wook.net/internal/cache"
+)
+
+// 목록 캐시 키에 포함되는 버전 카운터의 키입니다. movie가 추가, 수정, 삭제될 때마다 증가하므로
+// 이전 버전으로 저장된 목록은 더 이상 조회되지 않고 TTL이 지나면 사라집니다.
+const movieListVersionKey = "movies:list:version"
+
+// CacheStats는 캐시 적중률을 확인하기 위한 카운터입니다. expvar로 게시할 수 있도록 Snapshot()을 제공합니다.
+type CacheStats struct {
+	Hits          atomic.Int64
+	Misses        atomic.Int64
+	Errors        atomic.Int64
+	Invalidations atomic.Int64
+}
+
+func (s *CacheStats) Snapshot() map[string]int64 {
+	return map[string]int64{
+		"hits":          s.Hits.Load(),
+		"misses":        s.Misses.Load(),
+		"errors":        s.Errors.Load(),
+		"invalidations": s.Invalidations.Load(),
+	}
+}
+
+// CachedMovieModel은 다른 MovieStore를 감싸 Get()과 GetAll()의 결과를 캐시하는 read-through 캐시입니다.
+// 캐시 오류는 Stats에만 기록하고 원본 저장소로 되돌아가므로 캐시 장애가 요청 실패로 이어지지 않습니다.
+//
+// 조회와 수정이 동시에 일어나면 무효화 직후에 이전 값이 다시 저장될 수 있으므로, 오래된 값이
+// 남아 있을 수 있는 최대 시간은 TTL입니다. 수정 시에는 버전 검사가 데이터베이스에서 이루어지므로
+// 오래된 값을 기준으로 한 업데이트는 ErrEditConflict로 거부됩니다.
+type CachedMovieModel struct {
+	Store MovieStore
+	Cache cache.Cache
+	TTL   time.Duration
+	Stats *CacheStats
+}
+
+func NewCachedMovieModel(store MovieStore, c cache.Cache, ttl time.Duration) CachedMovieModel {
+	return CachedMovieModel{
+		Store: store,
+		Cache: c,
+		TTL:   ttl,
+		Stats: &CacheStats{},
+	}
+}
+
+func movieCacheKey(id int64) string {
+	return fmt.Sprintf("movie:%d", id)
+}
+
+// movieListCacheKey()는 검색 조건과 목록 버전으로 키를 만듭니다. 제목에 임의의 문자가 들어갈 수 있으므로 해시를 사용합니다.
+func movieListCacheKey(version int64, title string, genres []string, filters Filters) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q|%q|%d|%d|%q", title, genres, filters.Page, filters.PageSize, filters.Sort)))
+	return fmt.Sprintf("movies:list:%d:%s", version, hex.EncodeToString(sum[:16]))
+}
+
+func (m CachedMovieModel) Insert(ctx context.Context, movie *Movie) error {
+	err := m.Store.Insert(ctx, movie)
+	if err != nil {
+		return err
+	}
+
+	m.invalidate()
+	return nil
+}
+
+func (m CachedMovieModel) Get(ctx context.Context, id int64) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	key := movieCacheKey(id)
+
+	var movie Movie
+	if m.load(ctx, key, &movie) {
+		return &movie, nil
+	}
+
+	result, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.store(ctx, key, result)
+
+	return result, nil
+}
+
+func (m CachedMovieModel) Update(ctx context.Context, movie *Movie, editorID int64) error {
+	err := m.Store.Update(ctx, movie, editorID)
+	if err != nil {
+		return err
+	}
+
+	m.invalidate(movieCacheKey(movie.ID))
+	return nil
+}
+
+func (m CachedMovieModel) Delete(ctx context.Context, id int64) error {
+	err := m.Store.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	m.invalidate(movieCacheKey(id))
+	return nil
+}
+
+type cachedMovieList struct {
+	Movies   []*Movie
+	Metadata Metadata
+}
+
+func (m CachedMovieModel) GetAll(ctx context.Context, title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	version, err := m.Cache.Counter(ctx, movieListVersionKey)
+	if err != nil {
+		m.Stats.Errors.Add(1)
+		return m.Store.GetAll(ctx, title, genres, filters)
+	}
+
+	key := movieListCacheKey(version, title, genres, filters)
+
+	var list cachedMovieList
+	if m.load(ctx, key, &list) {
+		// gob은 빈 슬라이스를 nil로 디코딩하므로 JSON 응답이 null이 되지 않도록 되돌립니다.
+		if list.Movies == nil {
+			list.Movies = []*Movie{}
+		}
+		return list.Movies, list.Metadata, nil
+	}
+
+	movies, metadata, err := m.Store.GetAll(ctx, title, genres, filters)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	m.store(ctx, key, cachedMovieList{Movies: movies, Metadata: metadata})
+
+	return movies, metadata, nil
+}
+
+// GetAllForUser()는 데이터 내보내기에서만 사용하므로 캐시하지 않습니다.
+func (m CachedMovieModel) GetAllForUser(ctx context.Context, userID int64) ([]*Movie, error) {
+	return m.Store.GetAllForUser(ctx, userID)
+}
+
+// load()는 캐시된 값을 dst로 디코딩하고 성공하면 true를 반환합니다.
+func (m CachedMovieModel) load(ctx context.Context, key string, dst any) bool {
+	b, ok, err := m.Cache.Get(ctx, key)
+	if err != nil {
+		m.Stats.Errors.Add(1)
+		return false
+	}
+	if !ok {
+		m.Stats.Misses.Add(1)
+		return false
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(dst)
+	if err != nil {
+		m.Stats.Errors.Add(1)
+		return false
+	}
+
+	m.Stats.Hits.Add(1)
+	return true
+}
+
+func (m CachedMovieModel) store(ctx context.Context, key string, value any) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(value)
+	if err == nil {
+		err = m.Cache.Set(ctx, key, buf.Bytes(), m.TTL)
+	}
+	if err != nil {
+		m.Stats.Errors.Add(1)
+	}
+}
+
+// invalidate()는 주어진 키를 삭제하고 목록 버전을 올립니다. 변경은 이미 데이터베이스에 반영되었으므로
+// 무효화 실패는 오류로 반환하지 않고 기록만 합니다. 같은 이유로 클라이언트가 연결을 끊어도 무효화가
+// 취소되지 않도록 요청 컨텍스트 대신 새 컨텍스트를 사용합니다.
+func (m CachedMovieModel) invalidate(keys ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	m.Stats.Invalidations.Add(1)
+
+	if len(keys) > 0 {
+		err := m.Cache.Delete(ctx, keys...)
+		if err != nil {
+			m.Stats.Errors.Add(1)
+		}
+	}
+
+	_, err := m.Cache.Incr(ctx, movieListVersionKey)
+	if err != nil {
+		m.Stats.Errors.Add(1)
+	}
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"greenlight.wook.net/internal/cache"
+)
+
+// countingMovieStore는 호출 횟수를 세는 메모리 기반 MovieStore입니다.
+type countingMovieStore struct {
+	movies map[int64]*Movie
+	gets   int
+	lists  int
+}
+
+func (s *countingMovieStore) Insert(ctx context.Context, movie *Movie) error {
+	movie.ID = int64(len(s.movies) + 1)
+	movie.Version = 1
+	copied := *movie
+	s.movies[movie.ID] = &copied
+	return nil
+}
+
+func (s *countingMovieStore) Get(ctx context.Context, id int64) (*Movie, error) {
+	s.gets++
+	movie, ok := s.movies[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	copied := *movie
+	return &copied, nil
+}
+
+func (s *countingMovieStore) Update(ctx context.Context, movie *Movie, editorID int64) error {
+	stored, ok := s.movies[movie.ID]
+	if !ok || stored.Version != movie.Version {
+		return ErrEditConflict
+	}
+	movie.Version++
+	copied := *movie
+	s.movies[movie.ID] = &copied
+	return nil
+}
+
+func (s *countingMovieStore) Delete(ctx context.Context, id int64) error {
+	if _, ok := s.movies[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(s.movies, id)
+	return nil
+}
+
+func (s *countingMovieStore) GetAll(ctx context.Context, title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+	s.lists++
+	movies := []*Movie{}
+	for id := int64(1); id <= int64(len(s.movies)+1); id++ {
+		if movie, ok := s.movies[id]; ok {
+			copied := *movie
+			movies = append(movies, &copied)
+		}
+	}
+	return movies, calculateMetadata(len(movies), filters.Page, filters.PageSize), nil
+}
+
+func (s *countingMovieStore) GetAllForUser(ctx context.Context, userID int64) ([]*Movie, error) {
+	return nil, nil
+}
+
+func TestCachedMovieModel(t *testing.T) {
+	store := &countingMovieStore{movies: make(map[int64]*Movie)}
+	ctx := context.Background()
+	m := NewCachedMovieModel(store, cache.NewLRU(100), time.Minute)
+
+	movie := &Movie{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}}
+	if err := m.Insert(ctx, movie); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := m.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != "Moana" {
+			t.Errorf("Title = %q; want Moana", got.Title)
+		}
+	}
+	if store.gets != 1 {
+		t.Errorf("store.Get called %d times; want 1", store.gets)
+	}
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
+
+	for i := 0; i < 2; i++ {
+		movies, _, err := m.GetAll(ctx, "", []string{}, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(movies) != 1 {
+			t.Fatalf("len(movies) = %d; want 1", len(movies))
+		}
+	}
+	if store.lists != 1 {
+		t.Errorf("store.GetAll called %d times; want 1", store.lists)
+	}
+
+	// Update()는 해당 movie와 모든 목록 캐시를 무효화해야 합니다.
+	movie.Title = "Moana (2016)"
+	if err := m.Update(ctx, movie, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Get(ctx, movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Moana (2016)" || got.Version != 2 {
+		t.Errorf("Get() after Update() = %q v%d; want updated record", got.Title, got.Version)
+	}
+
+	movies, _, err := m.GetAll(ctx, "", []string{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.lists != 2 || movies[0].Title != "Moana (2016)" {
+		t.Errorf("GetAll() after Update() served a stale list")
+	}
+
+	if err := m.Delete(ctx, movie.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(ctx, movie.ID); err != ErrRecordNotFound {
+		t.Errorf("Get() after Delete() error = %v; want ErrRecordNotFound", err)
+	}
+
+	movies, _, err = m.GetAll(ctx, "", []string{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movies == nil || len(movies) != 0 {
+		t.Errorf("GetAll() after Delete() = %v; want empty non-nil slice", movies)
+	}
+
+	// 빈 목록을 캐시에서 읽을 때도 nil이 아닌 빈 슬라이스를 반환해야 합니다.
+	movies, _, err = m.GetAll(ctx, "", []string{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movies == nil {
+		t.Error("cached empty list decoded as nil")
+	}
+
+	stats := m.Stats.Snapshot()
+	if stats["hits"] == 0 || stats["misses"] == 0 || stats["invalidations"] != 3 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+}
@@ -18,42 +18,93 @@ type Movie struct {
 	Year      int32     `json:"year,omitempty"`
 	Runtime   Runtime   `json:"runtime,omitempty"`
 	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	Poster    *Poster   `json:"poster,omitempty"`
+	// CreatedBy는 movie를 등록한 사용자의 ID입니다. 사용자가 계정을 삭제하면 NULL이 되어
+	// movie는 남고 작성자 정보만 익명화됩니다.
+	CreatedBy *int64 `json:"-"`
+	Version   int32  `json:"version"`
+}
+
+// Poster는 movie 포스터 원본과 썸네일의 저장소 키를 담습니다. 데이터베이스에는 키만 저장하고,
+// URL은 응답을 보내기 전에 사용 중인 저장소 백엔드에 맞게 채워 넣습니다.
+type Poster struct {
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// posterColumns는 포스터가 없는 movie의 NULL 열을 스캔하기 위한 헬퍼입니다.
+type posterColumns struct {
+	key          sql.NullString
+	thumbnailKey sql.NullString
+}
+
+func (p *posterColumns) poster() *Poster {
+	if !p.key.Valid {
+		return nil
+	}
+	return &Poster{Key: p.key.String, ThumbnailKey: p.thumbnailKey.String}
+}
+
+func posterArgs(poster *Poster) (any, any) {
+	if poster == nil {
+		return nil, nil
+	}
+	return poster.Key, poster.ThumbnailKey
 }
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func (m MovieModel) Insert(movie *Movie) error {
+// Insert()는 movie와 첫 번째 버전의 스냅샷을 하나의 트랜잭션으로 저장합니다.
+func (m MovieModel) Insert(ctx context.Context, movie *Movie) error {
 
 	query := `
-		INSERT INTO movies (title, year, runtime, genres)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO movies (title, year, runtime, genres, created_by)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, version`
-	args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+	args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.CreatedBy}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	if err != nil {
+		return err
+	}
+
+	err = insertMovieVersion(ctx, tx, movie, movie.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m MovieModel) Get(id int64) (*Movie, error) {
+func (m MovieModel) Get(ctx context.Context, id int64) (*Movie, error) {
 
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
+		SELECT id, created_at, title, year, runtime, genres, poster_key, poster_thumbnail_key, version
 		FROM movies
 		WHERE id = $1`
 
 	var movie Movie
+	var poster posterColumns
 	// context.WithTimeout() 함수를 사용하여 3초의 타임아웃 기한이 있는 context.Context를 생성합니다.
-	// 빈 context.Background()를 '부모' 컨텍스트로 사용하고 있다는 점에 유의하세요.
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// 호출자가 전달한 요청 컨텍스트를 '부모' 컨텍스트로 사용하므로 클라이언트가 연결을 끊으면 쿼리도 취소됩니다.
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	// 중요한 것은, Get() 메서드가 반환되기 전에 컨텍스트를 취소하려면 defer를 사용해야 한다는 것입니다.
 	defer cancel()
 	// 쿼리를 실행하려면 QueryRowContext() 메서드를 사용하여 첫 번째 인수로 마감일이 포함된 컨텍스트를 전달합니다.
@@ -64,6 +115,8 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 		&movie.Year,
 		&movie.Runtime,
 		pq.Array(&movie.Genres),
+		&poster.key,
+		&poster.thumbnailKey,
 		&movie.Version,
 	)
 
@@ -76,29 +129,43 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 		}
 	}
 
+	movie.Poster = poster.poster()
+
 	return &movie, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// Update()는 버전이 일치하는 경우에만 movie를 수정하고, editorID를 작성자로 하는 새 버전의 스냅샷을
+// 같은 트랜잭션에서 저장합니다.
+func (m MovieModel) Update(ctx context.Context, movie *Movie, editorID int64) error {
 	query := `
 		UPDATE movies
-		SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET title = $1, year = $2, runtime = $3, genres = $4, poster_key = $5, poster_thumbnail_key = $6, version = version + 1
+		WHERE id = $7 AND version = $8
 		RETURNING version `
 
+	posterKey, posterThumbnailKey := posterArgs(movie.Poster)
+
 	args := []any{
 		movie.Title,
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		posterKey,
+		posterThumbnailKey,
 		movie.ID,
 		movie.Version, // 예상되는 movie version 추가
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -108,10 +175,15 @@ func (m MovieModel) Update(movie *Movie) error {
 		}
 	}
 
-	return nil
+	err = insertMovieVersion(ctx, tx, movie, &editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m MovieModel) Delete(id int64) error {
+func (m MovieModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
@@ -120,7 +192,7 @@ func (m MovieModel) Delete(id int64) error {
 		DELETE FROM movies
 		WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, id)
@@ -141,17 +213,17 @@ func (m MovieModel) Delete(id int64) error {
 }
 
 // 메타데이터 구조체를 반환하도록 함수 서명을 업데이트합니다.
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+func (m MovieModel) GetAll(ctx context.Context, title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
 	// 총 (필터링된) 레코드를 계산하는 창 함수를 포함하도록 SQL 쿼리를 업데이트합니다.
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+		SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, poster_key, poster_thumbnail_key, version
 		FROM movies
 		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND (genres @> $2 OR $2 = '{}')
 		ORDER BY %s %s, id ASC
 		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	args := []any{title, pq.Array(genres), filters.limit(), filters.offset()}
@@ -168,6 +240,7 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 
 	for rows.Next() {
 		var movie Movie
+		var poster posterColumns
 
 		err := rows.Scan(
 			&totalRecords, // window  함수의 카운트를 totalRecords로 스캔합니다.
@@ -177,12 +250,16 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 			&movie.Year,
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
+			&poster.key,
+			&poster.thumbnailKey,
 			&movie.Version,
 		)
 		if err != nil {
 			return nil, Metadata{}, err // 빈 메타데이터 구조체를 반환하도록 업데이트합니다.
 		}
 
+		movie.Poster = poster.poster()
+
 		movies = append(movies, &movie)
 	}
 
@@ -195,6 +272,57 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	return movies, metadata, nil
 }
 
+// GetAllForUser()는 특정 사용자가 등록한 모든 movie를 ID 순서로 반환합니다.
+func (m MovieModel) GetAllForUser(ctx context.Context, userID int64) ([]*Movie, error) {
+	query := `
+		SELECT id, created_at, title, year, runtime, genres, poster_key, poster_thumbnail_key, created_by, version
+		FROM movies
+		WHERE created_by = $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		var poster posterColumns
+
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&poster.key,
+			&poster.thumbnailKey,
+			&movie.CreatedBy,
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movie.Poster = poster.poster()
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
 func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(movie.Title != "", "title", "must be provided")
 	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
@@ -214,22 +342,26 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 
 type MockMovieModel struct{}
 
-func (m MockMovieModel) Insert(movie *Movie) error {
+func (m MockMovieModel) Insert(ctx context.Context, movie *Movie) error {
 	return nil
 }
 
-func (m MockMovieModel) Get(id int64) (*Movie, error) {
+func (m MockMovieModel) Get(ctx context.Context, id int64) (*Movie, error) {
 	return nil, nil
 }
 
-func (m MockMovieModel) Update(movie *Movie) error {
+func (m MockMovieModel) Update(ctx context.Context, movie *Movie, editorID int64) error {
 	return nil
 }
 
-func (m MockMovieModel) Delete(id int64) error {
+func (m MockMovieModel) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (m MockMovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+func (m MockMovieModel) GetAll(ctx context.Context, title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
 	return nil, Metadata{}, nil
 }
+
+func (m MockMovieModel) GetAllForUser(ctx context.Context, userID int64) ([]*Movie, error) {
+	return nil, nil
+}
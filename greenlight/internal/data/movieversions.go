@@ -0,0 +1,203 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieVersion은 특정 버전의 movie 내용을 담은 스냅샷입니다. movie가 생성되거나 수정될 때마다
+// 새 버전 번호로 하나씩 저장됩니다. 포스터는 교체되면 이전 파일이 삭제되므로 스냅샷에 포함하지 않습니다.
+type MovieVersion struct {
+	MovieID   int64     `json:"movie_id"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year"`
+	Runtime   Runtime   `json:"runtime"`
+	Genres    []string  `json:"genres"`
+}
+
+// MovieFieldChange는 두 버전 사이에서 값이 달라진 필드 하나입니다.
+type MovieFieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// insertMovieVersion()은 movie의 현재 내용을 createdBy가 작성한 movie.Version의 스냅샷으로 저장합니다.
+// movie 레코드의 변경과 원자적으로 기록되도록 항상 같은 트랜잭션 안에서 호출해야 합니다.
+func insertMovieVersion(ctx context.Context, tx *sql.Tx, movie *Movie, createdBy *int64) error {
+	query := `
+		INSERT INTO movie_versions (movie_id, version, title, year, runtime, genres, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := tx.ExecContext(ctx, query, movie.ID, movie.Version, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), createdBy)
+	return err
+}
+
+type MovieVersionModel struct {
+	DB *sql.DB
+}
+
+// GetAll()은 movie의 모든 버전을 오래된 순서로 반환합니다.
+func (m MovieVersionModel) GetAll(ctx context.Context, movieID int64) ([]*MovieVersion, error) {
+	query := `
+		SELECT movie_id, version, created_at, title, year, runtime, genres
+		FROM movie_versions
+		WHERE movie_id = $1
+		ORDER BY version`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*MovieVersion{}
+
+	for rows.Next() {
+		var version MovieVersion
+
+		err := rows.Scan(
+			&version.MovieID,
+			&version.Version,
+			&version.CreatedAt,
+			&version.Title,
+			&version.Year,
+			&version.Runtime,
+			pq.Array(&version.Genres),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, &version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetAllForUser()는 사용자가 작성한 모든 버전을 movie와 버전 순서대로 반환합니다.
+func (m MovieVersionModel) GetAllForUser(ctx context.Context, userID int64) ([]*MovieVersion, error) {
+	query := `
+		SELECT movie_id, version, created_at, title, year, runtime, genres
+		FROM movie_versions
+		WHERE created_by = $1
+		ORDER BY movie_id, version`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*MovieVersion{}
+
+	for rows.Next() {
+		var version MovieVersion
+
+		err := rows.Scan(
+			&version.MovieID,
+			&version.Version,
+			&version.CreatedAt,
+			&version.Title,
+			&version.Year,
+			&version.Runtime,
+			pq.Array(&version.Genres),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, &version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (m MovieVersionModel) Get(ctx context.Context, movieID int64, version int32) (*MovieVersion, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT movie_id, version, created_at, title, year, runtime, genres
+		FROM movie_versions
+		WHERE movie_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var mv MovieVersion
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&mv.MovieID,
+		&mv.Version,
+		&mv.CreatedAt,
+		&mv.Title,
+		&mv.Year,
+		&mv.Runtime,
+		pq.Array(&mv.Genres),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &mv, nil
+}
+
+// DiffMovieVersions()는 from에서 to로 바뀐 필드를 JSON 필드 이름과 함께 반환합니다.
+// 바뀐 필드가 없으면 빈 슬라이스를 반환합니다.
+func DiffMovieVersions(from, to *MovieVersion) []MovieFieldChange {
+	changes := []MovieFieldChange{}
+
+	if from.Title != to.Title {
+		changes = append(changes, MovieFieldChange{"title", from.Title, to.Title})
+	}
+	if from.Year != to.Year {
+		changes = append(changes, MovieFieldChange{"year", from.Year, to.Year})
+	}
+	if from.Runtime != to.Runtime {
+		changes = append(changes, MovieFieldChange{"runtime", from.Runtime, to.Runtime})
+	}
+	if !equalStrings(from.Genres, to.Genres) {
+		changes = append(changes, MovieFieldChange{"genres", from.Genres, to.Genres})
+	}
+
+	return changes
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package data
+
+import "testing"
+
+func TestDiffMovieVersions(t *testing.T) {
+	v1 := &MovieVersion{Version: 1, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}}
+	v2 := &MovieVersion{Version: 2, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}}
+	v3 := &MovieVersion{Version: 3, Title: "Moana", Year: 2017, Runtime: 107, Genres: []string{"adventure", "animation"}}
+
+	if changes := DiffMovieVersions(v1, v2); changes == nil || len(changes) != 0 {
+		t.Errorf("DiffMovieVersions(v1, v2) = %v; want empty non-nil slice", changes)
+	}
+
+	changes := DiffMovieVersions(v1, v3)
+	if len(changes) != 2 {
+		t.Fatalf("DiffMovieVersions(v1, v3) = %v; want 2 changes", changes)
+	}
+
+	if changes[0].Field != "year" || changes[0].From != int32(2016) || changes[0].To != int32(2017) {
+		t.Errorf("changes[0] = %+v; want year 2016 -> 2017", changes[0])
+	}
+	// 장르 순서 변경도 변경으로 취급합니다.
+	if changes[1].Field != "genres" {
+		t.Errorf("changes[1].Field = %q; want genres", changes[1].Field)
+	}
+}
@@ -14,6 +14,11 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	// ScopeExport 토큰은 개인 데이터 내보내기 파일을 이메일 링크로 내려받을 때 사용합니다.
+	ScopeExport = "export"
+	// ScopeMFAPending 토큰은 비밀번호는 확인했지만 아직 두 번째 인증 요소를 확인하지 않은 상태를 나타내며,
+	// 인증 토큰으로 교환하는 용도로만 사용할 수 있습니다.
+	ScopeMFAPending = "mfa-pending"
 )
 
 // 구조체 태그를 추가하여 JSON으로 인코딩할 때 구조체가 표시되는 방식을 제어합니다.
@@ -76,26 +81,26 @@ type TokenModel struct {
 }
 
 // New() 메서드는 새 토큰 구조체를 생성한 다음 토큰 테이블에 데이터를 삽입하는 바로 가기입니다.
-func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token, err := generateToken(userID, ttl, scope)
 
 	if err != nil {
 		return nil, err
 	}
 
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 	return token, err
 }
 
 // Insert()는 특정 토큰에 대한 데이터를 토큰 테이블에 추가합니다.
-func (m TokenModel) Insert(token *Token) error {
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `
 			INSERT INTO tokens (hash, user_id, expiry, scope)
 			VALUES ($1, $2, $3, $4)`
 
 	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, args...)
@@ -103,14 +108,52 @@ func (m TokenModel) Insert(token *Token) error {
 }
 
 // DeleteAllForUser()는 특정 사용자 및 범위에 대한 모든 토큰을 삭제합니다.
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
 	query := `
 			DELETE FROM tokens
 			WHERE scope = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// GetAllForUser()는 특정 사용자의 모든 토큰을 만료 시간 순서로 반환합니다.
+// 일반 텍스트는 저장되어 있지 않으므로 반환된 토큰의 Plaintext 필드는 비어 있습니다.
+func (m TokenModel) GetAllForUser(ctx context.Context, userID int64) ([]*Token, error) {
+	query := `
+			SELECT hash, user_id, expiry, scope
+			FROM tokens
+			WHERE user_id = $1
+			ORDER BY expiry`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		var token Token
+
+		err := rows.Scan(&token.Hash, &token.UserID, &token.Expiry, &token.Scope)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, &token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
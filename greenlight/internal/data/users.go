@@ -50,7 +50,7 @@ type password struct {
 // 데이터베이스에 사용자에 대한 새 레코드를 삽입합니다.
 // ID, created_at 및 버전 필드는 모두 데이터베이스에서 자동으로 생성되므로
 // movie을 생성할 때와 같은 방식으로 삽입 후 RETURNING 절을 사용하여 사용자 구조체로 읽어들입니다.
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
 
 	query := `
 		INSERT INTO users (name, email, password_hash, activated)
@@ -59,7 +59,7 @@ func (m UserModel) Insert(user *User) error {
 
 	args := []any{user.Name, user.Email, user.Password.hash, user.Activated}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	// 테이블에 이미 이 이메일 주소가 포함된 레코드가 있는 경우
@@ -78,10 +78,47 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
+// ID를 기준으로 사용자 세부 정보를 검색합니다. 일치하는 레코드가 없으면 ErrRecordNotFound 오류를 반환합니다.
+func (m UserModel) Get(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version
+		FROM users
+		WHERE id = $1`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
 // 사용자의 이메일 주소를 기준으로 데이터베이스에서 사용자 세부 정보를 검색합니다.
 // 이메일 열에 UNIQUE 제약 조건이 있으므로 이 SQL 쿼리는 하나의 레코드만 반환합니다
 // (또는 전혀 반환하지 않으며, 이 경우 ErrRecordNotFound 오류를 반환합니다).
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
 		SELECT id, created_at, name, email, password_hash, activated, version
 		FROM users
@@ -89,7 +126,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
@@ -117,7 +154,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 // 요청 주기 동안 경합 조건을 방지하기 위해 버전 필드에 대해 확인합니다. 또한
 // 업데이트를 수행할 때 원래 사용자 레코드를 삽입할 때와 마찬가지로
 // "users_email_key" 제약 조건 위반 여부도 확인합니다.
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
 	query := `
 		UPDATE users
 		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
@@ -133,7 +170,7 @@ func (m UserModel) Update(user *User) error {
 		user.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
@@ -151,6 +188,37 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
+// Delete()는 사용자 레코드를 삭제합니다. 토큰, 권한, API 키는 외래 키의 ON DELETE CASCADE로
+// 함께 삭제되고, 사용자가 등록한 movie의 created_by는 ON DELETE SET NULL로 익명화됩니다.
+func (m UserModel) Delete(ctx context.Context, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM users
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 // Set() 메서드는 일반 텍스트 비밀번호의 bcrypt 해시를 계산하고
 // 해시와 일반 텍스트 버전을 모두 구조체에 저장합니다.
 func (p *password) Set(plaintextPassword string) error {
@@ -214,7 +282,7 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	// 클라이언트가 제공한 일반 텍스트 토큰의 SHA-256 해시를 계산합니다.
 	// 이는 슬라이스가 아닌 길이 32의 바이트 *array*을 반환한다는 점을 기억하세요.
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
@@ -233,7 +301,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	// 토큰 만료를 확인하기 위해 현재 시간을 값으로 전달한다는 점을 주목하세요.
 	args := []any{tokenHash[:], tokenScope, time.Now()}
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	// 쿼리를 실행하여 반환값을 User 구조체로 스캔합니다.
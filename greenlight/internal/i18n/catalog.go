@@ -0,0 +1,76 @@
+package i18n
+
+// catalog는 언어별 번역 목록입니다. 새 메시지를 추가할 때는 영어 원문을 그대로 키로 사용하고,
+// 형식 문자열의 경우 번역에도 같은 순서와 개수의 서식 지정자를 사용해야 합니다.
+var catalog = map[string]map[string]string{
+	Korean: {
+		// cmd/api/errors.go
+		"the server encountered a problem and could not process your request":   "서버에 문제가 발생하여 요청을 처리할 수 없습니다.",
+		"the requested resource could not be found":                             "요청한 리소스를 찾을 수 없습니다.",
+		"the %s method is not supported for this resource":                      "이 리소스에는 %s 메서드가 지원되지 않습니다.",
+		"unable to update the record due to an edit conflict, please try again": "충돌로 인해 레코드를 업데이트할 수 없습니다. 다시 시도하세요.",
+		"rate limit exceeded": "요청 한도를 초과했습니다.",
+		"too many failed login attempts, please try again later":                             "로그인 실패 횟수가 너무 많습니다. 잠시 후 다시 시도하세요.",
+		"this account has been temporarily locked because of too many failed login attempts": "로그인 실패 횟수가 너무 많아 계정이 일시적으로 잠겼습니다.",
+		"invalid authentication credentials":                                                 "인증 정보가 올바르지 않습니다.",
+		"invalid or missing authentication token":                                            "인증 토큰이 없거나 올바르지 않습니다.",
+		"you must be authenticated to access this resource":                                  "이 리소스에 접근하려면 인증이 필요합니다.",
+		"your user account must be activated to access this resource":                        "이 리소스에 접근하려면 사용자 계정이 활성화되어 있어야 합니다.",
+		"your user account doesn't have the necessary permissions to access this resource":   "사용자 계정에 이 리소스에 접근하는 데 필요한 권한이 없습니다.",
+
+		// cmd/api/helpers.go의 readJSON()과 요청 본문 오류
+		"body contains badly-formed JSON (at character %d)":   "본문에 잘못된 형식의 JSON이 포함되어 있습니다(%d 문자에서)",
+		"body contains badly-formed JSON":                     "본문에 잘못된 형식의 JSON이 포함되어 있습니다",
+		"body contains incorrect JSON type for field %q":      "본문에 %q 필드에 대한 잘못된 JSON 유형이 있습니다",
+		"body contains incorrect JSON type (at character %d)": "본문에 잘못된 JSON 형식이 있습니다(%d 문자에서)",
+		"body must not be empty":                              "본문이 비어 있지 않아야 합니다",
+		"body contains unknown key %s":                        "본문에 알 수 없는 키 %s가 포함되어 있습니다",
+		"body must not be larger than %d bytes":               "본문은 %d bytes보다 크지 않아야 합니다",
+		"body must only contain a single JSON value":          "본문은 단일 JSON 값만 포함해야 합니다",
+		"body must be multipart/form-data":                    "본문은 multipart/form-data 형식이어야 합니다",
+		"invalid runtime format":                              "runtime format이 잘못되었습니다",
+
+		// internal/data의 유효성 검사 메시지
+		"must be provided":                     "반드시 입력해야 합니다",
+		"must be a valid email address":        "올바른 이메일 주소여야 합니다",
+		"must be at least 8 bytes long":        "8바이트 이상이어야 합니다",
+		"must not be more than 72 bytes long":  "72바이트를 넘을 수 없습니다",
+		"must not be more than 100 bytes long": "100바이트를 넘을 수 없습니다",
+		"must not be more than 500 bytes long": "500바이트를 넘을 수 없습니다",
+		"must be greater than 1888":            "1888보다 커야 합니다",
+		"must not be in the future":            "미래일 수 없습니다",
+		"must be a positive integer":           "양의 정수여야 합니다",
+		"must contain at least 1 genre":        "장르를 1개 이상 포함해야 합니다",
+		"must not contain more than 5 genres":  "장르는 5개를 넘을 수 없습니다",
+		"must not contain duplicate values":    "중복된 값을 포함할 수 없습니다",
+		"must be greater than zero":            "0 보다 커야만 합니다",
+		"must be a maximum of 10 million":      "최대 1000만 까지 요청할 수 있습니다",
+		"must be a maximum of 100":             "최대 100 까지 요청할 수 있습니다",
+		"invalid sort value":                   "잘못된 sort 값 입니다",
+		"must be 26 bytes long":                "26바이트여야 합니다",
+		"must be 52 bytes long":                "52바이트여야 합니다",
+		"must contain at least 1 permission":   "권한을 1개 이상 포함해야 합니다",
+		"must be in the future":                "미래 시점이어야 합니다",
+		"must be 6 digits long":                "6자리여야 합니다",
+		"must be an integer value":             "정수여야 합니다",
+
+		// cmd/api 핸들러의 유효성 검사 메시지
+		"a user with this email address already exists":               "이미 사용 중인 이메일 주소입니다",
+		"invalid or expired activation token":                         "활성화 토큰이 올바르지 않거나 만료되었습니다",
+		"must only contain permissions you hold":                      "보유한 권한만 포함할 수 있습니다",
+		"must be a JPEG, PNG or GIF image":                            "JPEG, PNG 또는 GIF 이미지여야 합니다",
+		"must be a valid image":                                       "올바른 이미지여야 합니다",
+		"must not be larger than %d bytes":                            "%d바이트보다 클 수 없습니다",
+		"must not be larger than %dx%d pixels":                        "%dx%d 픽셀보다 클 수 없습니다",
+		"must not have more than %d megapixels":                       "%d메가픽셀보다 클 수 없습니다",
+		"multi-factor authentication is already enabled":              "다중 인증이 이미 활성화되어 있습니다",
+		"multi-factor authentication enrollment has not been started": "다중 인증 등록이 시작되지 않았습니다",
+		"invalid or expired code":                                     "코드가 올바르지 않거나 만료되었습니다",
+		"is already the current version":                              "이미 현재 버전입니다",
+		"must provide either a code or a recovery code":               "코드 또는 복구 코드 중 하나를 입력해야 합니다",
+		"invalid origin %q":                                           "%q은(는) 올바른 출처가 아닙니다",
+		"origin %q must use http or https":                            "출처 %q은(는) http 또는 https를 사용해야 합니다",
+		"origin %q must not contain a path, query or credentials":     "출처 %q에는 경로, 쿼리, 인증 정보를 포함할 수 없습니다",
+		"origin %q may only use a wildcard as its leftmost label":     "출처 %q은(는) 가장 왼쪽 레이블에만 와일드카드를 사용할 수 있습니다",
+	},
+}
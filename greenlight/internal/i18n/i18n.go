@@ -0,0 +1,105 @@
+// i18n 패키지는 API 응답에 포함되는 메시지를 클라이언트의 Accept-Language 헤더에 맞는 언어로 번역합니다.
+// 메시지 카탈로그의 키는 영어 원문(또는 fmt 형식 문자열)이며, 번역이 없으면 원문을 그대로 사용합니다.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 지원하는 언어 태그입니다. 카탈로그의 원문이 영어이므로 English가 기본 언어입니다.
+const (
+	English = "en"
+	Korean  = "ko"
+)
+
+var Supported = []string{English, Korean}
+
+// Negotiate()는 Accept-Language 헤더 값에서 지원하는 언어 중 가중치(q)가 가장 높은 언어를 반환합니다.
+// 가중치가 같으면 헤더에 먼저 나온 언어를 선택하며, 일치하는 언어가 없으면 English를 반환합니다.
+func Negotiate(header string) string {
+	best, bestQ := English, 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q <= 0 || q <= bestQ {
+			continue
+		}
+
+		// "ko-KR"처럼 지역이 포함된 태그는 기본 언어 부분만 비교합니다.
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+
+		switch {
+		case primary == "*":
+			best, bestQ = English, q
+		case supported(primary):
+			best, bestQ = primary, q
+		}
+	}
+
+	return best
+}
+
+func supported(lang string) bool {
+	for _, s := range Supported {
+		if s == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate()는 카탈로그에서 key의 번역을 찾아 반환하고, 번역이 없으면 key를 그대로 반환합니다.
+func Translate(lang, key string) string {
+	if translated, ok := catalog[lang][key]; ok {
+		return translated
+	}
+	return key
+}
+
+// Sprintf()는 format을 번역한 뒤 args로 서식을 적용합니다.
+func Sprintf(lang, format string, args ...any) string {
+	return fmt.Sprintf(Translate(lang, format), args...)
+}
+
+// Message는 번역할 수 있는 형식 문자열과 인수입니다. error 인터페이스를 구현하므로 readJSON()처럼
+// 오류를 반환하는 함수에서 사용할 수 있으며, Error()는 영어 원문을 반환합니다.
+type Message struct {
+	Format string
+	Args   []any
+}
+
+// Errorf()는 fmt.Errorf()와 같지만 나중에 번역할 수 있는 *Message를 반환합니다.
+func Errorf(format string, args ...any) error {
+	return &Message{Format: format, Args: args}
+}
+
+func (m *Message) Error() string {
+	return fmt.Sprintf(m.Format, m.Args...)
+}
+
+func (m *Message) Translate(lang string) string {
+	return Sprintf(lang, m.Format, m.Args...)
+}
+
+// TranslateError()는 err가 *Message이면 형식 문자열을 번역하고,
+// 그렇지 않으면 err.Error()를 카탈로그 키로 사용합니다.
+func TranslateError(lang string, err error) string {
+	var msg *Message
+	if errors.As(err, &msg) {
+		return msg.Translate(lang)
+	}
+	return Translate(lang, err.Error())
+}
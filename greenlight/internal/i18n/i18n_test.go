@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", English},
+		{"ko", Korean},
+		{"ko-KR,ko;q=0.9,en-US;q=0.8,en;q=0.7", Korean},
+		{"en-US,en;q=0.9,ko;q=0.8", English},
+		{"fr-FR, ko;q=0.5", Korean},
+		{"ko;q=0.2, en;q=0.8", English},
+		{"ko;q=0, *", English},
+		{"fr, de", English},
+		{"ko;q=abc, en;q=0.1", English},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q; want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+var verbRX = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// 번역이 원문과 같은 서식 지정자를 같은 순서로 사용하지 않으면 fmt가 인수를 잘못 채웁니다.
+func TestCatalogVerbs(t *testing.T) {
+	for lang, messages := range catalog {
+		for key, translated := range messages {
+			want := fmt.Sprint(verbRX.FindAllString(key, -1))
+			got := fmt.Sprint(verbRX.FindAllString(translated, -1))
+			if got != want {
+				t.Errorf("%s: %q uses verbs %s; want %s", lang, translated, got, want)
+			}
+		}
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	err := fmt.Errorf("decoding: %w", Errorf("body must not be larger than %d bytes", 1024))
+
+	if got, want := TranslateError(Korean, err), "본문은 1024 bytes보다 크지 않아야 합니다"; got != want {
+		t.Errorf("TranslateError(ko) = %q; want %q", got, want)
+	}
+	if got, want := TranslateError(English, err), "body must not be larger than 1024 bytes"; got != want {
+		t.Errorf("TranslateError(en) = %q; want %q", got, want)
+	}
+	if got, want := TranslateError(Korean, errors.New("must be provided")), "반드시 입력해야 합니다"; got != want {
+		t.Errorf("TranslateError(ko, plain) = %q; want %q", got, want)
+	}
+	if got, want := TranslateError(Korean, errors.New("not in the catalog")), "not in the catalog"; got != want {
+		t.Errorf("TranslateError(ko, unknown) = %q; want %q", got, want)
+	}
+}
@@ -2,8 +2,11 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"html/template"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/go-mail/mail/v2"
@@ -87,3 +90,16 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 	}
 	return nil
 }
+
+// Ping() 메서드는 메일을 보내지 않고 SMTP 서버에 TCP 연결이 가능한지만 확인합니다.
+// 준비 상태 검사에서 사용하며, 연결은 즉시 닫습니다.
+func (m Mailer) Ping(ctx context.Context) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(m.dialer.Host, strconv.Itoa(m.dialer.Port)))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
@@ -0,0 +1,364 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"greenlight.wook.net/internal/jsonlog"
+)
+
+var (
+	ErrDirty          = errors.New("migrate: database is dirty, fix the failed migration manually and reset schema_migrations")
+	ErrUnknownVersion = errors.New("migrate: unknown migration version")
+)
+
+// migrate CLI와 같은 테이블 이름과 열 구조를 사용하므로 기존 데이터베이스를 그대로 이어서 사용할 수 있습니다.
+const migrationsTable = "schema_migrations"
+
+// advisoryLockSalt는 migrate CLI가 잠금 ID를 만들 때 사용하는 값과 같습니다.
+// 같은 ID를 사용하므로 CLI와 내장 러너가 동시에 실행되어도 서로를 기다리게 됩니다.
+const advisoryLockSalt uint32 = 1486364155
+
+var filenameRX = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+
+// Migration은 하나의 버전에 해당하는 up/down SQL 파일 쌍을 나타냅니다.
+type Migration struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	up      string
+	down    string
+}
+
+// MigrationStatus는 status 서브커맨드에서 각 마이그레이션의 적용 여부를 보여줍니다.
+type MigrationStatus struct {
+	Migration
+	Applied bool `json:"applied"`
+}
+
+type Status struct {
+	Version    int64             `json:"version"`
+	Dirty      bool              `json:"dirty"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// Migrator는 임베디드 파일 시스템에 포함된 SQL 파일을 데이터베이스에 적용합니다.
+type Migrator struct {
+	db         *sql.DB
+	fsys       fs.FS
+	logger     *jsonlog.Logger
+	migrations []Migration
+}
+
+// New()는 fsys의 최상위 디렉터리에서 마이그레이션 파일을 읽어 버전 순서로 정렬합니다.
+// 같은 버전에 up 파일이 없거나 버전이 중복되거나 SQL 파일 이름이 형식에 맞지 않으면 오류를 반환합니다.
+func New(db *sql.DB, fsys fs.FS, logger *jsonlog.Logger) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		// SQL이 아닌 파일은 건너뛰지만, 이름이 잘못된 SQL 파일은 조용히 적용되지 않는 일이 없도록 오류로 처리합니다.
+		if path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		matches := filenameRX.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migrate: invalid file name %q, want <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migrate: invalid version in %q", entry.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = mig
+		}
+
+		if mig.Name != matches[2] {
+			return nil, fmt.Errorf("migrate: duplicate version %d (%s, %s)", version, mig.Name, matches[2])
+		}
+
+		switch matches[3] {
+		case "up":
+			mig.up = entry.Name()
+		case "down":
+			mig.down = entry.Name()
+		}
+	}
+
+	m := &Migrator{db: db, fsys: fsys, logger: logger}
+
+	for _, mig := range byVersion {
+		if mig.up == "" {
+			return nil, fmt.Errorf("migrate: missing up file for version %d", mig.Version)
+		}
+		m.migrations = append(m.migrations, *mig)
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+
+	return m, nil
+}
+
+// Latest()는 포함된 마이그레이션 중 가장 높은 버전을 반환합니다.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version()은 schema_migrations 테이블에 기록된 현재 버전과 dirty 플래그를 반환합니다.
+// 테이블이 아직 없거나 비어 있으면 버전 0을 반환합니다.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	var exists bool
+
+	err := m.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, migrationsTable).Scan(&exists)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !exists {
+		return 0, false, nil
+	}
+
+	return readVersion(ctx, m.db)
+}
+
+// Status()는 현재 버전과 함께 각 마이그레이션의 적용 여부를 반환합니다.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Version: version, Dirty: dirty}
+	for _, mig := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Migration: mig,
+			Applied:   mig.Version <= version,
+		})
+	}
+
+	return status, nil
+}
+
+// Up()은 아직 적용되지 않은 모든 마이그레이션을 적용합니다.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down()은 적용된 모든 마이그레이션을 되돌립니다.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.To(ctx, 0)
+}
+
+// To()는 데이터베이스를 주어진 버전까지 올리거나 내립니다. 버전 0은 모든 마이그레이션을 되돌린다는 의미입니다.
+// 전체 작업은 advisory lock을 잡은 하나의 연결에서 실행되므로 동시에 배포된 여러 인스턴스가 경쟁하지 않습니다.
+func (m *Migrator) To(ctx context.Context, target int64) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint NOT NULL PRIMARY KEY,
+			dirty boolean NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	// 잠금을 얻은 뒤에 버전을 읽어야 다른 인스턴스가 방금 적용한 마이그레이션을 다시 실행하지 않습니다.
+	current, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return ErrDirty
+	}
+
+	if current != 0 && m.index(current) < 0 {
+		return fmt.Errorf("%w: database is at version %d", ErrUnknownVersion, current)
+	}
+
+	switch {
+	case target > current:
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > target {
+				continue
+			}
+
+			err = m.apply(ctx, conn, mig, mig.up, mig.Version)
+			if err != nil {
+				return err
+			}
+		}
+
+	case target < current:
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current || mig.Version <= target {
+				continue
+			}
+
+			if mig.down == "" {
+				return fmt.Errorf("migrate: missing down file for version %d", mig.Version)
+			}
+
+			var previous int64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+
+			err = m.apply(ctx, conn, mig, mig.down, previous)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply()는 하나의 SQL 파일을 실행하고 schema_migrations를 같은 트랜잭션 안에서 갱신합니다.
+// PostgreSQL은 DDL도 트랜잭션으로 처리하므로 실패하면 dirty 상태를 남기지 않고 롤백됩니다.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, file string, newVersion int64) error {
+	body, err := fs.ReadFile(m.fsys, file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(string(body)) != "" {
+		_, err = tx.ExecContext(ctx, string(body))
+		if err != nil {
+			return fmt.Errorf("migrate: %s: %w", path.Base(file), err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+
+	// migrate CLI는 모든 마이그레이션을 되돌렸을 때 테이블을 비워 두므로 여기서도 행을 추가하지 않습니다.
+	if newVersion > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, newVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.logger.PrintInfo("applied migration", map[string]string{
+		"file":    file,
+		"version": strconv.FormatInt(newVersion, 10),
+	})
+
+	return nil
+}
+
+// lock()은 세션 수준 advisory lock을 획득하고 해제 함수를 반환합니다.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	var database, schema string
+
+	err := conn.QueryRowContext(ctx, `SELECT current_database(), current_schema()`).Scan(&database, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	id := advisoryLockID(database, schema)
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// 요청 컨텍스트가 이미 취소되었더라도 잠금은 반드시 해제해야 하므로 새 컨텍스트를 사용합니다.
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, id)
+		if err != nil {
+			m.logger.PrintError(err, nil)
+		}
+	}, nil
+}
+
+func (m *Migrator) index(version int64) int {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func readVersion(ctx context.Context, q queryRower) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+
+	return version, dirty, nil
+}
+
+func advisoryLockID(database, schema string) int64 {
+	sum := crc32.ChecksumIEEE([]byte(strings.Join([]string{schema, migrationsTable, database}, "\x00")))
+	return int64(sum * advisoryLockSalt)
+}
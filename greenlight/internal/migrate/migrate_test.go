@@ -0,0 +1,319 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"greenlight.wook.net/internal/jsonlog"
+)
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		wantErr  string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"10_c.up.sql":     file(""),
+				"2_b.up.sql":      file(""),
+				"2_b.down.sql":    file(""),
+				"000001_a.up.sql": file(""),
+				"README.md":       file("not a migration"),
+				"old/3_x.up.sql":  file(""),
+			},
+			versions: []int64{1, 2, 10},
+		},
+		{
+			name:    "duplicate version",
+			fsys:    fstest.MapFS{"1_a.up.sql": file(""), "1_b.up.sql": file("")},
+			wantErr: "duplicate version 1",
+		},
+		{
+			name:    "missing up file",
+			fsys:    fstest.MapFS{"1_a.up.sql": file(""), "2_b.down.sql": file("")},
+			wantErr: "missing up file for version 2",
+		},
+		{
+			name:     "missing down file",
+			fsys:     fstest.MapFS{"1_a.up.sql": file("")},
+			versions: []int64{1},
+		},
+		{
+			name:    "version zero",
+			fsys:    fstest.MapFS{"0_a.up.sql": file("")},
+			wantErr: "invalid version",
+		},
+		{
+			name:    "version out of range",
+			fsys:    fstest.MapFS{"99999999999999999999_a.up.sql": file("")},
+			wantErr: "invalid version",
+		},
+		{
+			name:    "no direction",
+			fsys:    fstest.MapFS{"1_a.sql": file("")},
+			wantErr: "invalid file name",
+		},
+		{
+			name:    "no version",
+			fsys:    fstest.MapFS{"create_movies.up.sql": file("")},
+			wantErr: "invalid file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(nil, tt.fsys, jsonlog.New(io.Discard, jsonlog.LevelOff))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var versions []int64
+			for _, mig := range m.migrations {
+				versions = append(versions, mig.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("got versions %v, want %v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestTo(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_a.up.sql":   file("up 1"),
+		"1_a.down.sql": file("down 1"),
+		"2_b.up.sql":   file("up 2"),
+		"2_b.down.sql": file("down 2"),
+		"3_c.up.sql":   file("up 3"),
+		"3_c.down.sql": file("down 3"),
+	}
+
+	db := &fakeDB{}
+	m, err := New(sql.OpenDB(db), fsys, jsonlog.New(io.Discard, jsonlog.LevelOff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		run     func() error
+		applied []string
+		version int64
+	}{
+		{"up", func() error { return m.Up(ctx) }, []string{"up 1", "up 2", "up 3"}, 3},
+		{"up again", func() error { return m.Up(ctx) }, nil, 3},
+		{"to 1", func() error { return m.To(ctx, 1) }, []string{"down 3", "down 2"}, 1},
+		{"to 2", func() error { return m.To(ctx, 2) }, []string{"up 2"}, 2},
+		{"down", func() error { return m.Down(ctx) }, []string{"down 2", "down 1"}, 0},
+	}
+
+	for _, step := range steps {
+		err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		if applied := db.takeApplied(); !reflect.DeepEqual(applied, step.applied) {
+			t.Errorf("%s: ran %q, want %q", step.name, applied, step.applied)
+		}
+
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != step.version || dirty {
+			t.Errorf("%s: at version %d (dirty %t), want %d", step.name, version, dirty, step.version)
+		}
+	}
+
+	if err := m.To(ctx, 7); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("unknown target: got %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestToStopsAtFailures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_a.up.sql":   file("up 1"),
+		"2_b.up.sql":   file("fail"),
+		"2_b.down.sql": file("down 2"),
+		"3_c.up.sql":   file("up 3"),
+	}
+
+	db := &fakeDB{}
+	m, err := New(sql.OpenDB(db), fsys, jsonlog.New(io.Discard, jsonlog.LevelOff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	err = m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_b.up.sql") {
+		t.Fatalf("got %v, want the failing file named", err)
+	}
+
+	// 실패한 마이그레이션은 롤백되므로 데이터베이스는 마지막으로 성공한 버전에 머뭅니다.
+	version, dirty, _ := m.Version(ctx)
+	if applied := db.takeApplied(); !reflect.DeepEqual(applied, []string{"up 1"}) || version != 1 || dirty {
+		t.Errorf("ran %q and ended at version %d (dirty %t)", applied, version, dirty)
+	}
+
+	err = m.Down(ctx)
+	if err == nil || !strings.Contains(err.Error(), "missing down file for version 1") {
+		t.Errorf("down without a down file: got %v", err)
+	}
+}
+
+// fakeDB는 Migrator가 실행하는 구문만 이해하는 가짜 database/sql 드라이버입니다.
+// 그 밖의 구문은 마이그레이션 본문으로 취급하여 "fail"이면 오류를 반환하고,
+// 나머지는 트랜잭션이 커밋될 때 applied에 기록합니다.
+type fakeDB struct {
+	mu         sync.Mutex
+	tableReady bool
+	version    int64 // schema_migrations가 비어 있으면 0입니다.
+	applied    []string
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (db *fakeDB) takeApplied() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	applied := db.applied
+	db.applied = nil
+	return applied
+}
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+// fakeTx는 커밋될 때까지 트랜잭션의 변경 사항을 보관합니다.
+type fakeTx struct {
+	conn    *fakeConn
+	version int64
+	applied []string
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.tx = &fakeTx{conn: c, version: c.db.version}
+	return c.tx, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	db := tx.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.version = tx.version
+	db.applied = append(db.applied, tx.applied...)
+	tx.conn.tx = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.tx = nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "pg_advisory"):
+	case strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		c.db.tableReady = true
+	case c.tx == nil:
+		return nil, errors.New("migration statement outside a transaction: " + query)
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		c.tx.version = 0
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.tx.version = args[0].Value.(int64)
+	case query == "fail":
+		return nil, errors.New("syntax error")
+	default:
+		c.tx.applied = append(c.tx.applied, query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "current_database()"):
+		return &fakeRows{cols: []string{"current_database", "current_schema"}, rows: [][]driver.Value{{"greenlight", "public"}}}, nil
+	case strings.Contains(query, "to_regclass"):
+		return &fakeRows{cols: []string{"exists"}, rows: [][]driver.Value{{c.db.tableReady}}}, nil
+	case strings.HasPrefix(query, "SELECT version, dirty FROM schema_migrations"):
+		version := c.db.version
+		if c.tx != nil {
+			version = c.tx.version
+		}
+		rows := &fakeRows{cols: []string{"version", "dirty"}}
+		if version != 0 {
+			rows.rows = [][]driver.Value{{version, false}}
+		}
+		return rows, nil
+	}
+
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local은 로컬 디스크의 디렉터리에 파일을 저장합니다. 저장된 파일은 baseURL 아래에서
+// 제공된다고 가정하며, 애플리케이션은 같은 디렉터리를 정적 파일로 서비스해야 합니다.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocal(dir, baseURL string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put()은 같은 디렉터리에 임시 파일을 만든 뒤 이름을 바꾸므로 읽는 쪽에서 절반만 쓰인 파일을 보지 않습니다.
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Delete()는 이미 없는 파일에 대해서는 오류를 반환하지 않습니다.
+func (s *Local) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Local) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+func (s *Local) path(key string) (string, error) {
+	err := validateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config는 S3 호환 객체 스토리지에 연결하는 데 필요한 설정입니다.
+// Endpoint에는 "https://s3.ap-northeast-2.amazonaws.com"이나 MinIO 같은 로컬 서버 주소를 사용할 수 있습니다.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL은 클라이언트에게 노출할 객체 URL의 접두사입니다. 비어 있으면 Endpoint/Bucket을 사용합니다.
+	PublicURL string
+}
+
+// S3는 AWS Signature Version 4로 서명한 요청을 path-style URL로 보내는 Storage 구현입니다.
+// 외부 SDK 없이 표준 라이브러리만 사용합니다.
+type S3 struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.Region == "" {
+		return nil, fmt.Errorf("storage: s3 endpoint, region and bucket must be provided")
+	}
+
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = cfg.Endpoint + "/" + cfg.Bucket
+	}
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+
+	return &S3{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+// Put()은 서명에 본문의 SHA-256 해시가 필요하므로 본문 전체를 메모리로 읽습니다.
+// 포스터처럼 크기가 제한된 업로드에만 사용해야 합니다.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp)
+	}
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, s.responseError(resp)
+	}
+}
+
+// Delete()는 S3와 마찬가지로 존재하지 않는 객체를 삭제해도 오류를 반환하지 않습니다.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s.responseError(resp)
+	}
+}
+
+func (s *S3) URL(key string) string {
+	return s.cfg.PublicURL + "/" + escapePath(key)
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	err := validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Endpoint+"/"+s.cfg.Bucket+"/"+escapePath(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	hash := sha256.Sum256(body)
+	s.sign(req, hex.EncodeToString(hash[:]), s.now())
+
+	return s.client.Do(req)
+}
+
+func (s *S3) responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("storage: s3 %s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, bytes.TrimSpace(msg))
+}
+
+// sign()은 요청에 AWS Signature Version 4 Authorization 헤더를 추가합니다.
+// Host 헤더와 요청에 설정된 모든 헤더가 서명에 포함됩니다.
+func (s *S3) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escapePath()는 SigV4 규칙에 따라 슬래시를 제외한 모든 예약 문자를 퍼센트 인코딩합니다.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i := range segments {
+		segments[i] = uriEncode(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(key)+"="+uriEncode(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// uriEncode()는 영문자, 숫자와 '-', '.', '_', '~'를 제외한 모든 바이트를 %XX 형식으로 인코딩합니다.
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
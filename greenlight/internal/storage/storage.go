@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Storage는 업로드된 파일을 저장하는 백엔드가 구현해야 하는 인터페이스입니다.
+// 키는 "posters/1/abc.jpg"처럼 슬래시로 구분된 상대 경로입니다.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL()은 클라이언트가 파일을 내려받을 수 있는 URL을 반환합니다.
+	URL(key string) string
+}
+
+// validateKey()는 키가 정규화된 상대 경로인지 확인하여 백엔드 밖의 경로에 접근하지 못하도록 합니다.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+	return nil
+}
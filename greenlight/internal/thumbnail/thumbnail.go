@@ -0,0 +1,91 @@
+package thumbnail
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Fit()은 원본 비율을 유지하면서 maxWidth x maxHeight 안에 들어가도록 축소한 이미지를 반환합니다.
+// 각 대상 픽셀은 대응하는 원본 영역의 평균값(box filter)으로 계산하므로 단순한
+// 최근접 샘플링보다 계단 현상이 적습니다. 원본이 이미 충분히 작으면 크기를 바꾸지 않습니다.
+// 원본 전체를 RGBA로 복사하지 않고 한 줄씩 변환하므로 추가로 필요한 메모리는 원본 한 줄과 결과 이미지뿐입니다.
+func Fit(src image.Image, maxWidth, maxHeight int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	dw, dh := sw, sh
+	if dw > maxWidth {
+		dh = dh * maxWidth / dw
+		dw = maxWidth
+	}
+	if dh > maxHeight {
+		dw = dw * maxHeight / dh
+		dh = maxHeight
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if sw == 0 || sh == 0 {
+		return dst
+	}
+
+	// 대상 열마다 대응하는 원본 열의 범위는 모든 행에서 같으므로 미리 계산해 둡니다.
+	xs := make([]int, dw+1)
+	for x := 0; x <= dw; x++ {
+		xs[x] = x * sw / dw
+	}
+
+	row := image.NewRGBA(image.Rect(0, 0, sw, 1))
+	sums := make([]uint32, dw*4)
+
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := (y + 1) * sh / dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for i := range sums {
+			sums[i] = 0
+		}
+
+		for sy := y0; sy < y1; sy++ {
+			draw.Draw(row, row.Rect, src, image.Pt(b.Min.X, b.Min.Y+sy), draw.Src)
+
+			for x := 0; x < dw; x++ {
+				x0, x1 := xs[x], xs[x+1]
+				if x1 <= x0 {
+					x1 = x0 + 1
+				}
+
+				for i := x0 * 4; i < x1*4; i += 4 {
+					sums[x*4] += uint32(row.Pix[i])
+					sums[x*4+1] += uint32(row.Pix[i+1])
+					sums[x*4+2] += uint32(row.Pix[i+2])
+					sums[x*4+3] += uint32(row.Pix[i+3])
+				}
+			}
+		}
+
+		for x := 0; x < dw; x++ {
+			x0, x1 := xs[x], xs[x+1]
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			n := uint32((x1 - x0) * (y1 - y0))
+
+			j := y*dst.Stride + x*4
+			dst.Pix[j] = uint8(sums[x*4] / n)
+			dst.Pix[j+1] = uint8(sums[x*4+1] / n)
+			dst.Pix[j+2] = uint8(sums[x*4+2] / n)
+			dst.Pix[j+3] = uint8(sums[x*4+3] / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,92 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{"portrait poster", 1000, 1500, 200, 300},
+		{"wide image", 1600, 900, 200, 112},
+		{"tall image", 300, 1800, 50, 300},
+		{"already small", 150, 100, 150, 100},
+		{"exact fit", 200, 300, 200, 300},
+		{"one pixel high strip", 8000, 1, 200, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+
+			got := Fit(src, 200, 300).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH || got.Min != (image.Point{}) {
+				t.Errorf("Fit(%dx%d) = %v; want %dx%d at the origin", tt.width, tt.height, got, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestFitAveragesPixels(t *testing.T) {
+	// 흑백 체크무늬를 절반으로 줄이면 각 픽셀은 네 픽셀의 평균인 회색이 되어야 합니다.
+	src := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				src.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	dst := Fit(src, 2, 2)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			if got := dst.RGBAAt(x, y); got != (color.RGBA{127, 127, 127, 255}) {
+				t.Errorf("pixel (%d, %d) = %v; want mid grey", x, y, got)
+			}
+		}
+	}
+}
+
+func TestFitSubImage(t *testing.T) {
+	// 원점이 (0, 0)이 아닌 이미지도 자신의 영역만 사용해야 합니다.
+	src := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x >= 10 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	dst := Fit(src.SubImage(image.Rect(10, 10, 20, 20)), 5, 5)
+	if dst.Bounds() != image.Rect(0, 0, 5, 5) {
+		t.Fatalf("bounds = %v; want 5x5", dst.Bounds())
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("pixel (0, 0) = %v; want red from the sub-image", got)
+	}
+}
+
+func TestFitYCbCr(t *testing.T) {
+	// JPEG 디코더가 반환하는 YCbCr 이미지도 RGBA로 변환하여 축소해야 합니다.
+	src := image.NewYCbCr(image.Rect(0, 0, 400, 600), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 255
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = 128, 128
+	}
+
+	dst := Fit(src, 200, 300)
+	if got := dst.RGBAAt(100, 150); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("pixel = %v; want white", got)
+	}
+}
@@ -0,0 +1,128 @@
+// totp 패키지는 RFC 6238의 시간 기반 일회용 비밀번호(TOTP)를 구현합니다.
+// 인증 앱과의 호환성을 위해 HMAC-SHA1, 6자리, 30초 주기만 지원합니다.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// Skew는 클라이언트와 서버의 시계 차이를 고려하여 앞뒤로 허용하는 주기 수입니다.
+	Skew = 1
+)
+
+var ErrInvalidSecret = errors.New("totp: invalid secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret()은 RFC 4226이 권장하는 160비트 난수를 패딩 없는 base32 문자열로 반환합니다.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// ProvisioningURI()는 인증 앱이 QR 코드로 읽을 수 있는 otpauth:// URI를 반환합니다.
+func ProvisioningURI(secret, issuer, account string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
+
+// Counter()는 주어진 시각이 속한 시간 주기 번호를 반환합니다.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code()는 주어진 시각의 코드를 반환합니다.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, Counter(t), Digits), nil
+}
+
+// Validate()는 code가 주어진 시각 전후 Skew 주기 안의 코드와 일치하는지 확인하고, 일치하면
+// 해당 주기 번호를 반환합니다. 호출자는 같은 코드를 다시 사용할 수 없도록 이 번호를 기록해야 합니다.
+func Validate(secret, code string, t time.Time) (int64, bool, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+
+	counter := Counter(t)
+
+	for i := -Skew; i <= Skew; i++ {
+		expected := hotp(key, counter+int64(i), Digits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + int64(i), true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// hotp()는 RFC 4226의 HOTP 값을 digits 자리 문자열로 계산합니다.
+func hotp(key []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// 동적 잘라내기(dynamic truncation): 마지막 바이트의 하위 4비트를 오프셋으로 사용합니다.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
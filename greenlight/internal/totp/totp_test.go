@@ -0,0 +1,104 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 부록 B의 SHA1 테스트 벡터입니다. RFC는 8자리 코드를 사용하므로 하위 6자리와 비교합니다.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		got, err := Code(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := tt.code[len(tt.code)-Digits:]; got != want {
+			t.Errorf("Code(%d) = %s; want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	code, err := Code(rfc6238Secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		code string
+		want bool
+	}{
+		{"same period", now, code, true},
+		{"previous period", now.Add(Period), code, true},
+		{"next period", now.Add(-Period), code, true},
+		{"too old", now.Add(2 * Period), code, false},
+		{"wrong code", now, "000000", code == "000000"},
+		{"wrong length", now, code[:5], false},
+	}
+
+	for _, tt := range tests {
+		counter, ok, err := Validate(rfc6238Secret, tt.code, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.want {
+			t.Errorf("%s: Validate() = %t; want %t", tt.name, ok, tt.want)
+		}
+		if ok && counter != Counter(now) {
+			t.Errorf("%s: counter = %d; want %d", tt.name, counter, Counter(now))
+		}
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	_, _, err := Validate("not base32!", "123456", time.Unix(0, 0))
+	if err != ErrInvalidSecret {
+		t.Errorf("Validate() error = %v; want ErrInvalidSecret", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "Greenlight", "alice@example.com")
+
+	for _, part := range []string{"otpauth://totp/Greenlight:alice@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Greenlight", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("ProvisioningURI() = %q; missing %q", uri, part)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(secret) != 32 {
+		t.Errorf("len(secret) = %d; want 32", len(secret))
+	}
+
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Errorf("Code() with generated secret: %v", err)
+	}
+}
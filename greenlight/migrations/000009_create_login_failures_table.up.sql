@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+    scope text NOT NULL,
+    subject text NOT NULL,
+    failures integer NOT NULL DEFAULT 0,
+    last_failed_at timestamp with time zone NOT NULL,
+    blocked_until timestamp with time zone,
+    locked bool NOT NULL DEFAULT false,
+    PRIMARY KEY (scope, subject)
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS movie_versions (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text[] NOT NULL,
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    PRIMARY KEY (movie_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS movie_versions_created_by_idx ON movie_versions (created_by);
+
+-- Snapshot the current state of existing movies. Only an unedited movie's
+-- snapshot can be attributed, to the user who created it.
+INSERT INTO movie_versions (movie_id, version, title, year, runtime, genres, created_by)
+SELECT id, version, title, year, runtime, genres, CASE WHEN version = 1 THEN created_by END FROM movies
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+// migrations 패키지는 이 디렉터리의 SQL 마이그레이션 파일을 바이너리에 포함합니다.
+// 파일 이름은 migrate CLI와 동일한 <버전>_<이름>.<up|down>.sql 형식을 따릅니다.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,7 +15,7 @@ import (
 type Auth struct {
 	Issuer        string
 	Audience      string
-	Secret        string
+	Keys          *KeySet
 	TokenExpiry   time.Duration
 	RefreshExpiry time.Duration
 	CookieDomain  string
@@ -22,61 +24,116 @@ type Auth struct {
 }
 
 type jwtUser struct {
-	ID        int    `json:"ID"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
+	ID        int      `json:"ID"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Roles     []string `json:"roles"`
 }
 
 type TokenPairs struct {
 	Token        string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+
+	// server-side details of the refresh token, stored so it can be rotated and revoked
+	RefreshTokenID string    `json:"-"`
+	FamilyID       string    `json:"-"`
+	RefreshExpiry  time.Time `json:"-"`
 }
 
 type Claims struct {
+	Type     string   `json:"typ,omitempty"`
+	FamilyID string   `json:"fam,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (j *Auth) GenerateTokenPair(user *jwtUser) (TokenPairs, error) {
-	// Create a token
-	token := jwt.New(jwt.SigningMethodHS256)
+// HasRole reports whether the token was issued to a user with role.
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshTokenType is the typ claim of refresh tokens, so they can't be used as access tokens.
+const refreshTokenType = "refresh"
 
+// newTokenID returns a random identifier for the jti and family claims.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateTokenPair creates an access token and a refresh token for user. The refresh
+// token joins familyID, or starts a new family when familyID is empty (a fresh login).
+func (j *Auth) GenerateTokenPair(user *jwtUser, familyID string) (TokenPairs, error) {
 	// Set the claims
-	claims := token.Claims.(jwt.MapClaims)
+	claims := jwt.MapClaims{}
 	claims["name"] = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
 	claims["sub"] = fmt.Sprint(user.ID)
 	claims["aud"] = j.Audience
 	claims["iss"] = j.Issuer
 	claims["iat"] = time.Now().UTC().Unix()
 	claims["typ"] = "JWT"
+	claims["roles"] = user.Roles
 
 	// Set the expiry for JWT
 	claims["exp"] = time.Now().UTC().Add(j.TokenExpiry).Unix()
 
 	// Create a signed token
-	signedAccessToken, err := token.SignedString([]byte(j.Secret))
+	signedAccessToken, err := j.Keys.Sign(claims)
 	if err != nil {
 		return TokenPairs{}, err
 	}
 
 	// Create a refresh token and set claims
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
-	refreshTokenClaims := refreshToken.Claims.(jwt.MapClaims)
+	tokenID, err := newTokenID()
+	if err != nil {
+		return TokenPairs{}, err
+	}
+
+	if familyID == "" {
+		familyID, err = newTokenID()
+		if err != nil {
+			return TokenPairs{}, err
+		}
+	}
+
+	refreshExpiry := time.Now().UTC().Add(j.RefreshExpiry)
+
+	refreshTokenClaims := jwt.MapClaims{}
 	refreshTokenClaims["sub"] = fmt.Sprint(user.ID)
+	refreshTokenClaims["aud"] = j.Audience
+	refreshTokenClaims["iss"] = j.Issuer
 	refreshTokenClaims["iat"] = time.Now().UTC().Unix()
+	refreshTokenClaims["typ"] = refreshTokenType
+	refreshTokenClaims["jti"] = tokenID
+	refreshTokenClaims["fam"] = familyID
 
 	// Set the expiry for the refresh token
-	refreshTokenClaims["exp"] = time.Now().UTC().Add(j.RefreshExpiry).Unix()
+	refreshTokenClaims["exp"] = refreshExpiry.Unix()
 
 	// Create signed refresh roken
-	signedRefreshToken, err := refreshToken.SignedString([]byte(j.Secret))
+	signedRefreshToken, err := j.Keys.Sign(refreshTokenClaims)
 	if err != nil {
 		return TokenPairs{}, err
 	}
 
 	// Create TokenPairs and populate with signed tokens
 	var tokenPairs = TokenPairs{
-		Token:        signedAccessToken,
-		RefreshToken: signedRefreshToken,
+		Token:          signedAccessToken,
+		RefreshToken:   signedRefreshToken,
+		RefreshTokenID: tokenID,
+		FamilyID:       familyID,
+		RefreshExpiry:  refreshExpiry,
 	}
 	// Return TokenPairs
 	return tokenPairs, nil
@@ -137,12 +194,7 @@ func (j *Auth) GetTokenFromHeaderAndVerify(w http.ResponseWriter, r *http.Reques
 	claims := &Claims{}
 
 	// parse the token
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(j.Secret), nil
-	})
+	_, err := jwt.ParseWithClaims(token, claims, j.Keys.Keyfunc)
 
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "token is expired by") {
@@ -155,5 +207,39 @@ func (j *Auth) GetTokenFromHeaderAndVerify(w http.ResponseWriter, r *http.Reques
 		return "", nil, errors.New("invalid issuer")
 	}
 
+	if !claims.VerifyAudience(j.Audience, true) {
+		return "", nil, errors.New("invalid audience")
+	}
+
+	if claims.Type == refreshTokenType {
+		return "", nil, errors.New("refresh token used as access token")
+	}
+
 	return token, claims, nil
 }
+
+// ParseRefreshToken verifies a refresh token's signature, expiry, issuer, audience
+// and type, and returns its claims. Whether the token has already been used or
+// revoked is checked against the database by the caller.
+func (j *Auth) ParseRefreshToken(token string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, j.Keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != j.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if !claims.VerifyAudience(j.Audience, true) {
+		return nil, errors.New("invalid audience")
+	}
+
+	if claims.Type != refreshTokenType || claims.ID == "" || claims.FamilyID == "" {
+		return nil, errors.New("not a refresh token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxPage         = 10_000_000
+)
+
+// readMovieFilter reads the listing parameters of /movies and /admin/movies:
+// page, page_size, sort, rating, year_from, year_to and genres. rating and
+// genres take comma separated lists.
+func readMovieFilter(qs url.Values) (models.MovieFilter, error) {
+	var (
+		filter = models.MovieFilter{Page: 1, PageSize: defaultPageSize, Sort: "title"}
+		err    error
+	)
+
+	filter.Page, err = readInt(qs, "page", filter.Page)
+	if err != nil {
+		return filter, err
+	}
+	if filter.Page < 1 || filter.Page > maxPage {
+		return filter, fmt.Errorf("page must be between 1 and %d", maxPage)
+	}
+
+	filter.PageSize, err = readInt(qs, "page_size", filter.PageSize)
+	if err != nil {
+		return filter, err
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxPageSize {
+		return filter, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+	}
+
+	if qs.Has("sort") {
+		filter.Sort = qs.Get("sort")
+		if !permittedValue(filter.Sort, models.MovieSortSafelist...) {
+			return filter, errors.New("sort must be one of " + strings.Join(models.MovieSortSafelist, ", "))
+		}
+	}
+
+	for _, rating := range readCSV(qs, "rating") {
+		if !permittedValue(rating, models.MPAARatings...) {
+			return filter, errors.New("rating must be one of " + strings.Join(models.MPAARatings, ", "))
+		}
+		filter.Ratings = append(filter.Ratings, rating)
+	}
+
+	filter.YearFrom, err = readInt(qs, "year_from", 0)
+	if err != nil {
+		return filter, err
+	}
+	filter.YearTo, err = readInt(qs, "year_to", 0)
+	if err != nil {
+		return filter, err
+	}
+	if filter.YearFrom < 0 || filter.YearTo < 0 {
+		return filter, errors.New("release years must not be negative")
+	}
+	if filter.YearFrom != 0 && filter.YearTo != 0 && filter.YearFrom > filter.YearTo {
+		return filter, errors.New("year_from must not be after year_to")
+	}
+
+	for _, s := range readCSV(qs, "genres") {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 1 {
+			return filter, errors.New("genres must be a comma separated list of genre ids")
+		}
+		filter.GenreIDs = append(filter.GenreIDs, id)
+	}
+
+	return filter, nil
+}
+
+// readInt returns the integer value of key, or defaultValue when it is missing.
+func readInt(qs url.Values, key string, defaultValue int) (int, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New(key + " must be an integer")
+	}
+
+	return i, nil
+}
+
+// readCSV splits the comma separated values of key, skipping empty ones.
+func readCSV(qs url.Values, key string) []string {
+	var values []string
+	for _, v := range strings.Split(qs.Get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func permittedValue(value string, permitted ...string) bool {
+	for _, p := range permitted {
+		if value == p {
+			return true
+		}
+	}
+	return false
+}
+
+// paginationLinks returns a Link header value pointing at the first, previous,
+// next and last pages of a listing, keeping the rest of the request's query.
+func paginationLinks(u *url.URL, metadata models.Metadata) string {
+	if metadata.TotalRecords == 0 {
+		return ""
+	}
+
+	link := func(page int, rel string) string {
+		qs := u.Query()
+		qs.Set("page", strconv.Itoa(page))
+		qs.Set("page_size", strconv.Itoa(metadata.PageSize))
+
+		target := url.URL{Path: u.Path, RawQuery: qs.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+	}
+
+	// a page past the end links back to the last page that has movies
+	links := []string{link(metadata.FirstPage, "first")}
+	if metadata.CurrentPage > metadata.FirstPage {
+		prev := metadata.CurrentPage - 1
+		if prev > metadata.LastPage {
+			prev = metadata.LastPage
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if metadata.CurrentPage < metadata.LastPage {
+		links = append(links, link(metadata.CurrentPage+1, "next"))
+	}
+	links = append(links, link(metadata.LastPage, "last"))
+
+	return strings.Join(links, ", ")
+}
+
+// listMovies writes the page of movies the request's query selects, with its
+// metadata in the body and links to the neighbouring pages in a Link header.
+func (app *application) listMovies(w http.ResponseWriter, r *http.Request) {
+	filter, err := readMovieFilter(r.URL.Query())
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	movies, metadata, err := app.DB.ListMovies(filter)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if movies == nil {
+		movies = []*models.Movie{}
+	}
+
+	headers := http.Header{}
+	if links := paginationLinks(r.URL, metadata); links != "" {
+		headers.Set("Link", links)
+	}
+
+	payload := struct {
+		Movies   []*models.Movie `json:"movies"`
+		Metadata models.Metadata `json:"metadata"`
+	}{movies, metadata}
+
+	_ = app.writeJSON(w, http.StatusOK, payload, headers)
+}
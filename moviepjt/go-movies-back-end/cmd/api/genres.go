@@ -0,0 +1,256 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgconn"
+)
+
+// maxGenreLength is the size of the genres.genre column.
+const maxGenreLength = 255
+
+var (
+	errGenreNotFound = errors.New("genre not found")
+	errGenreTaken    = errors.New("a genre with this name already exists")
+	errGenreInUse    = errors.New("genre is in use")
+)
+
+// readGenreName reads the name of a genre from the request body, trimmed and
+// checked for length. Any error is reported to the client.
+func (app *application) readGenreName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var requestPayload struct {
+		Genre string `json:"genre"`
+	}
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return "", false
+	}
+
+	name := strings.TrimSpace(requestPayload.Genre)
+
+	switch {
+	case name == "":
+		app.errorJSON(w, errors.New("genre is required"), http.StatusUnprocessableEntity)
+		return "", false
+	case utf8.RuneCountInString(name) > maxGenreLength:
+		app.errorJSON(w, fmt.Errorf("genre must not be more than %d characters long", maxGenreLength), http.StatusUnprocessableEntity)
+		return "", false
+	}
+
+	return name, true
+}
+
+// genreNameTaken reports whether another genre than exceptID has the name,
+// ignoring case like the unique index on genres.
+func (app *application) genreNameTaken(name string, exceptID int) (bool, error) {
+	genres, err := app.DB.AllGenres()
+	if err != nil {
+		return false, err
+	}
+
+	for _, g := range genres {
+		if g.ID != exceptID && strings.EqualFold(g.Genre, name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isUniqueViolation reports whether err comes from a unique constraint, which
+// catches a name taken between genreNameTaken and the write.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (app *application) InsertGenre(w http.ResponseWriter, r *http.Request) {
+	name, ok := app.readGenreName(w, r)
+	if !ok {
+		return
+	}
+
+	taken, err := app.genreNameTaken(name, 0)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		app.errorJSON(w, errGenreTaken, http.StatusConflict)
+		return
+	}
+
+	genre := models.Genre{
+		Genre:     name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	genre.ID, err = app.DB.InsertGenre(genre)
+	if err != nil {
+		if isUniqueViolation(err) {
+			app.errorJSON(w, errGenreTaken, http.StatusConflict)
+			return
+		}
+
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := JSONResponse{
+		Error:   false,
+		Message: "genre created",
+		Data:    genre,
+	}
+
+	app.writeJSON(w, http.StatusCreated, resp)
+}
+
+// UpdateGenre renames a genre.
+func (app *application) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errGenreNotFound, http.StatusNotFound)
+		return
+	}
+
+	genre, err := app.DB.OneGenre(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.errorJSON(w, errGenreNotFound, http.StatusNotFound)
+			return
+		}
+
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	name, ok := app.readGenreName(w, r)
+	if !ok {
+		return
+	}
+
+	taken, err := app.genreNameTaken(name, id)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		app.errorJSON(w, errGenreTaken, http.StatusConflict)
+		return
+	}
+
+	genre.Genre = name
+	genre.UpdatedAt = time.Now()
+
+	err = app.DB.UpdateGenre(*genre)
+	if err != nil {
+		if isUniqueViolation(err) {
+			app.errorJSON(w, errGenreTaken, http.StatusConflict)
+			return
+		}
+
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := JSONResponse{
+		Error:   false,
+		Message: "genre updated",
+		Data:    genre,
+	}
+
+	app.writeJSON(w, http.StatusAccepted, resp)
+}
+
+// DeleteGenre deletes a genre no movie has. With ?reassign_to=<genre id> the
+// movies of the genre are moved to that genre first, in the same transaction.
+func (app *application) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errGenreNotFound, http.StatusNotFound)
+		return
+	}
+
+	reassignTo := 0
+	if s := r.URL.Query().Get("reassign_to"); s != "" {
+		reassignTo, err = strconv.Atoi(s)
+		if err != nil || reassignTo < 1 {
+			app.errorJSON(w, errors.New("reassign_to must be a genre id"), http.StatusUnprocessableEntity)
+			return
+		}
+		if reassignTo == id {
+			app.errorJSON(w, errors.New("reassign_to must be another genre"), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	errUnknownTarget := errors.New("the genre in reassign_to does not exist")
+
+	err = app.DB.WithTx(func(tx repository.DatabaseRepo) error {
+		// locks the genre so no movie picks it up between the count and the delete
+		_, err := tx.OneGenre(id)
+		if err != nil {
+			return err
+		}
+
+		if reassignTo != 0 {
+			_, err = tx.OneGenre(reassignTo)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errUnknownTarget
+			}
+			if err != nil {
+				return err
+			}
+
+			err = tx.ReassignGenre(id, reassignTo)
+			if err != nil {
+				return err
+			}
+		}
+
+		count, err := tx.GenreMovieCount(id)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w, %d movie(s) have it; pass reassign_to=<genre id> to move them to another genre", errGenreInUse, count)
+		}
+
+		return tx.DeleteGenre(id)
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNoRows):
+		app.errorJSON(w, errGenreNotFound, http.StatusNotFound)
+		return
+	case errors.Is(err, errUnknownTarget):
+		app.errorJSON(w, err, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, errGenreInUse):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	default:
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := JSONResponse{
+		Error:   false,
+		Message: "genre deleted",
+	}
+
+	app.writeJSON(w, http.StatusAccepted, resp)
+}
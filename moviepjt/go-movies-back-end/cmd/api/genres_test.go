@@ -0,0 +1,146 @@
+package main
+
+import (
+	"backend/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGenreAdministration(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	editor, _ := login(t, app, "admin@example.com")
+	viewer, _ := login(t, app, "viewer@example.com")
+
+	routes := []struct{ method, target string }{
+		{http.MethodPost, "/admin/genres"},
+		{http.MethodPatch, "/admin/genres/1"},
+		{http.MethodDelete, "/admin/genres/1"},
+	}
+
+	t.Run("requires the editor role", func(t *testing.T) {
+		for _, route := range routes {
+			rr := send(t, app, route.method, route.target, map[string]string{"genre": "Western"}, "")
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s without a token: got status %d", route.method, route.target, rr.Code)
+			}
+
+			rr = send(t, app, route.method, route.target, map[string]string{"genre": "Western"}, viewer)
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("%s %s as a viewer: got status %d", route.method, route.target, rr.Code)
+			}
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		rr := send(t, app, http.MethodPost, "/admin/genres", map[string]string{"genre": "  Western "}, editor)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		var resp struct{ Data models.Genre }
+		decode(t, rr, &resp)
+		if resp.Data.ID == 0 || resp.Data.Genre != "Western" {
+			t.Errorf("got %+v", resp.Data)
+		}
+
+		if g, err := db.OneGenre(resp.Data.ID); err != nil || g.Genre != "Western" {
+			t.Errorf("stored genre: got %+v, %v", g, err)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		rr := send(t, app, http.MethodPatch, "/admin/genres/3", map[string]string{"genre": "Thriller"}, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		// changing only the case of its own name is not a clash
+		rr = send(t, app, http.MethodPatch, "/admin/genres/3", map[string]string{"genre": "THRILLER"}, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("case change: got status %d: %s", rr.Code, rr.Body)
+		}
+
+		if g, _ := db.OneGenre(horrorID); g.Genre != "THRILLER" {
+			t.Errorf("stored genre: got %q", g.Genre)
+		}
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			body any
+			want int
+		}{
+			{"taken", map[string]string{"genre": "drama"}, http.StatusConflict},
+			{"empty", map[string]string{"genre": "   "}, http.StatusUnprocessableEntity},
+			{"too long", map[string]string{"genre": strings.Repeat("x", 256)}, http.StatusUnprocessableEntity},
+			{"unknown field", map[string]string{"name": "Western"}, http.StatusBadRequest},
+		} {
+			rr := send(t, app, http.MethodPost, "/admin/genres", tt.body, editor)
+			if rr.Code != tt.want {
+				t.Errorf("create %s: got status %d, want %d", tt.name, rr.Code, tt.want)
+			}
+
+			rr = send(t, app, http.MethodPatch, "/admin/genres/2", tt.body, editor)
+			if rr.Code != tt.want {
+				t.Errorf("rename %s: got status %d, want %d", tt.name, rr.Code, tt.want)
+			}
+		}
+
+		rr := send(t, app, http.MethodPatch, "/admin/genres/99", map[string]string{"genre": "Western 2"}, editor)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("unknown genre: got status %d", rr.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		// Comedy is a genre of Raiders of the Lost Ark
+		rr := send(t, app, http.MethodDelete, "/admin/genres/2", nil, editor)
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "reassign_to") {
+			t.Fatalf("genre in use: got status %d: %s", rr.Code, rr.Body)
+		}
+		if _, err := db.OneGenre(comedyID); err != nil {
+			t.Fatal("a genre in use was deleted")
+		}
+
+		for _, query := range []string{"?reassign_to=2", "?reassign_to=abc", "?reassign_to=99"} {
+			rr := send(t, app, http.MethodDelete, "/admin/genres/2"+query, nil, editor)
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: got status %d", query, rr.Code)
+			}
+		}
+
+		rr = send(t, app, http.MethodDelete, "/admin/genres/3", nil, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("unused genre: got status %d: %s", rr.Code, rr.Body)
+		}
+
+		rr = send(t, app, http.MethodDelete, "/admin/genres/3", nil, editor)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("deleted twice: got status %d", rr.Code)
+		}
+	})
+
+	t.Run("delete and reassign", func(t *testing.T) {
+		rr := send(t, app, http.MethodDelete, "/admin/genres/1?reassign_to=2", nil, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		if _, err := db.OneGenre(dramaID); err == nil {
+			t.Error("the genre was not deleted")
+		}
+
+		for _, id := range []int{highlanderID, raidersID} {
+			movie, err := db.OneMovie(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(movie.Genres) != 1 || movie.Genres[0].ID != comedyID {
+				t.Errorf("movie %d: got genres %+v, want only Comedy", id, movie.Genres)
+			}
+		}
+	})
+}
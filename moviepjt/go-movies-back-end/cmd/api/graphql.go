@@ -0,0 +1,130 @@
+package main
+
+import (
+	"backend/internal/graph"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlResponseType is the media type of the GraphQL-over-HTTP spec. Clients
+// that accept it get status codes that reflect whether the request could run;
+// clients that only accept application/json always get 200 for a well-formed request.
+const graphqlResponseType = "application/graphql-response+json"
+
+// moviesGraphQL serves GraphQL over HTTP. A GET takes query, variables,
+// operationName and extensions from the URL and can only run queries. A POST takes
+// either a JSON object with the same members or, for older clients, the raw query
+// sent as application/graphql. A bearer token is optional and only needed for mutations.
+func (app *application) moviesGraphQL(w http.ResponseWriter, r *http.Request) {
+	req, err := app.readGraphQLRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if r.Header.Get("Authorization") != "" {
+		_, claims, err := app.auth.GetTokenFromHeaderAndVerify(w, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := strconv.Atoi(claims.Subject)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ctx = graph.WithCaller(ctx, &graph.Caller{UserID: userID, Roles: claims.Roles})
+	}
+
+	resp, err := app.graph.Do(ctx, req, r.Method == http.MethodPost)
+	if err != nil {
+		if errors.Is(err, graph.ErrMutationNotAllowed) {
+			w.Header().Set("Allow", http.MethodPost)
+			app.errorJSON(w, err, http.StatusMethodNotAllowed)
+			return
+		}
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeGraphQL(w, r, resp)
+}
+
+// readGraphQLRequest decodes a GraphQL request from the URL of a GET or the body of a POST.
+func (app *application) readGraphQLRequest(w http.ResponseWriter, r *http.Request) (graph.Request, error) {
+	var req graph.Request
+
+	if r.Method == http.MethodGet {
+		qs := r.URL.Query()
+		req.Query = qs.Get("query")
+		req.OperationName = qs.Get("operationName")
+
+		if v := qs.Get("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &req.Variables)
+			if err != nil {
+				return req, errors.New("variables must be a JSON object")
+			}
+		}
+
+		if v := qs.Get("extensions"); v != "" {
+			err := json.Unmarshal([]byte(v), &req.Extensions)
+			if err != nil {
+				return req, errors.New("extensions must be a JSON object")
+			}
+		}
+
+		return req, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		err := app.readJSON(w, r, &req)
+		return req, err
+	case "application/graphql":
+		q, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1024*1024))
+		req.Query = string(q)
+		return req, err
+	default:
+		return req, errors.New("content type must be application/json or application/graphql")
+	}
+}
+
+// writeGraphQL sends a result in the media type the client asked for.
+func (app *application) writeGraphQL(w http.ResponseWriter, r *http.Request, resp *graphql.Result) {
+	out, err := json.Marshal(resp)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	contentType := "application/json"
+	status := http.StatusOK
+
+	if strings.Contains(r.Header.Get("Accept"), graphqlResponseType) {
+		contentType = graphqlResponseType
+
+		// A result without data means the request never ran, e.g. it failed to
+		// parse or validate; errors raised by resolvers still come back with a 200.
+		if resp.Data == nil && len(resp.Errors) > 0 {
+			status = http.StatusBadRequest
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(out)
+}
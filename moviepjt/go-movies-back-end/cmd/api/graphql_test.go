@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	app, repo := newTestApplication(t)
+
+	t.Run("raw application/graphql body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graph", strings.NewReader(`{ list { title } }`))
+		req.Header.Set("Content-Type", "application/graphql")
+
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		var resp graphqlResponse
+		decode(t, rr, &resp)
+
+		var list []struct{ Title string }
+		json.Unmarshal(resp.Data["list"], &list)
+		if len(list) != 2 {
+			t.Errorf("got %s", rr.Body)
+		}
+	})
+
+	t.Run("variables and genres", func(t *testing.T) {
+		rr := send(t, app, http.MethodPost, "/graph", map[string]any{
+			"query":     `query Search($t: String) { search(titleContains: $t) { title genres { genre } } }`,
+			"variables": map[string]any{"t": "lost"},
+		}, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		var resp graphqlResponse
+		decode(t, rr, &resp)
+
+		var search []struct {
+			Title  string
+			Genres []struct{ Genre string }
+		}
+		json.Unmarshal(resp.Data["search"], &search)
+		if len(search) != 1 || len(search[0].Genres) != 2 || search[0].Genres[0].Genre != "Comedy" {
+			t.Errorf("got %s", rr.Body)
+		}
+	})
+
+	t.Run("genres of a list load in one batch", func(t *testing.T) {
+		before := repo.GenresForMoviesCalls()
+
+		rr := send(t, app, http.MethodPost, "/graph", map[string]any{"query": `{ list { genres { genre } } }`}, "")
+		if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "errors") {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		if calls := repo.GenresForMoviesCalls() - before; calls != 1 {
+			t.Errorf("GenresForMovies called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("get over GET", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/graph?query="+url.QueryEscape(`{ get(id: 1) { title } genres { genre } }`), nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+		if !strings.Contains(rr.Body.String(), "Highlander") || !strings.Contains(rr.Body.String(), "Horror") {
+			t.Errorf("got %s", rr.Body)
+		}
+	})
+
+	t.Run("invalid query status depends on Accept", func(t *testing.T) {
+		for accept, want := range map[string]int{
+			"application/json":                  http.StatusOK,
+			"application/graphql-response+json": http.StatusBadRequest,
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/graph?query="+url.QueryEscape(`{ nope }`), nil)
+			req.Header.Set("Accept", accept)
+
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != want {
+				t.Errorf("Accept %s: got status %d, want %d", accept, rr.Code, want)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != accept {
+				t.Errorf("Accept %s: got Content-Type %s", accept, ct)
+			}
+		}
+	})
+
+	t.Run("depth limit", func(t *testing.T) {
+		app.graph.MaxDepth = 1
+		defer func() { app.graph.MaxDepth = 15 }()
+
+		rr := send(t, app, http.MethodPost, "/graph", map[string]any{"query": `{ list { title } }`}, "")
+
+		var resp graphqlResponse
+		decode(t, rr, &resp)
+		if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "depth") {
+			t.Errorf("got %s", rr.Body)
+		}
+	})
+
+	t.Run("complexity limit", func(t *testing.T) {
+		app.graph.MaxComplexity = 3
+		defer func() { app.graph.MaxComplexity = 500 }()
+
+		rr := send(t, app, http.MethodPost, "/graph", map[string]any{"query": `{ list { id title } }`}, "")
+		if strings.Contains(rr.Body.String(), "errors") {
+			t.Fatalf("at the limit: got %s", rr.Body)
+		}
+
+		rr = send(t, app, http.MethodPost, "/graph", map[string]any{"query": `{ list { id title genres { genre } } }`}, "")
+
+		var resp graphqlResponse
+		decode(t, rr, &resp)
+		if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+			t.Errorf("got %s", rr.Body)
+		}
+	})
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	query := `{ list { id } }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + hex.EncodeToString(sum[:]) + `"}}`
+	target := "/graph?extensions=" + url.QueryEscape(extensions)
+
+	rr := send(t, app, http.MethodGet, target, nil, "")
+
+	var resp graphqlResponse
+	decode(t, rr, &resp)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "PERSISTED_QUERY_NOT_FOUND" {
+		t.Fatalf("unknown hash: got %s", rr.Body)
+	}
+
+	rr = send(t, app, http.MethodPost, "/graph", map[string]any{
+		"query":      `{ list { title } }`,
+		"extensions": json.RawMessage(extensions),
+	}, "")
+	decode(t, rr, &resp)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "PERSISTED_QUERY_HASH_MISMATCH" {
+		t.Fatalf("mismatched hash: got %s", rr.Body)
+	}
+
+	rr = send(t, app, http.MethodPost, "/graph", map[string]any{
+		"query":      query,
+		"extensions": json.RawMessage(extensions),
+	}, "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "errors") {
+		t.Fatalf("registering: got status %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = send(t, app, http.MethodGet, target, nil, "")
+	resp = graphqlResponse{}
+	decode(t, rr, &resp)
+	if len(resp.Errors) != 0 || resp.Data["list"] == nil {
+		t.Errorf("known hash: got %s", rr.Body)
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	editor, _ := login(t, app, "admin@example.com")
+	viewer, _ := login(t, app, "viewer@example.com")
+
+	insert := map[string]any{
+		"query": `mutation Insert($in: MovieInput!) { insertMovie(input: $in) { id title genres { genre } } }`,
+		"variables": map[string]any{"in": map[string]any{
+			"title":        "Alien",
+			"release_date": "1979-05-25",
+			"runtime":      117,
+			"mpaa_rating":  "R",
+			"genre_ids":    []int{horrorID},
+		}},
+	}
+
+	t.Run("not over GET", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/graph?query="+url.QueryEscape(`mutation { updateMovie(id: 1, input: {title: "x"}) { id } }`), nil, editor)
+		if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != http.MethodPost {
+			t.Errorf("got status %d, Allow %q", rr.Code, rr.Header().Get("Allow"))
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"anonymous", "", "authentication required"},
+		{"without the editor role", viewer, "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(t, app, http.MethodPost, "/graph", insert, tt.token)
+
+			var resp graphqlResponse
+			decode(t, rr, &resp)
+			if len(resp.Errors) != 1 || resp.Errors[0].Message != tt.want {
+				t.Errorf("got %s", rr.Body)
+			}
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		rr := send(t, app, http.MethodPost, "/graph", insert, "not-a-token")
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d", rr.Code)
+		}
+	})
+
+	t.Run("insert and update", func(t *testing.T) {
+		rr := send(t, app, http.MethodPost, "/graph", insert, editor)
+
+		var resp graphqlResponse
+		decode(t, rr, &resp)
+		if len(resp.Errors) != 0 {
+			t.Fatalf("insert: got %s", rr.Body)
+		}
+
+		var inserted struct {
+			ID     int
+			Genres []struct{ Genre string }
+		}
+		json.Unmarshal(resp.Data["insertMovie"], &inserted)
+		if len(inserted.Genres) != 1 || inserted.Genres[0].Genre != "Horror" {
+			t.Errorf("insert: got %s", rr.Body)
+		}
+
+		rr = send(t, app, http.MethodPost, "/graph", map[string]any{
+			"query":     `mutation Update($id: Int!) { updateMovie(id: $id, input: {runtime: 116}) { runtime genres { genre } } }`,
+			"variables": map[string]any{"id": inserted.ID},
+		}, editor)
+		resp = graphqlResponse{}
+		decode(t, rr, &resp)
+		if len(resp.Errors) != 0 {
+			t.Fatalf("update: got %s", rr.Body)
+		}
+
+		movie, err := db.OneMovie(inserted.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movie.RunTime != 116 || movie.Title != "Alien" || len(movie.Genres) != 1 {
+			t.Errorf("update changed more than the runtime: %+v", movie)
+		}
+	})
+}
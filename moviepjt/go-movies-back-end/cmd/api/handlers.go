@@ -1,20 +1,15 @@
 package main
 
 import (
-	"backend/internal/graph"
 	"backend/internal/models"
-	"encoding/json"
+	"backend/internal/repository"
 	"errors"
-	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 func (app *application) Home(w http.ResponseWriter, r *http.Request) {
@@ -32,13 +27,7 @@ func (app *application) Home(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) AllMovies(w http.ResponseWriter, r *http.Request) {
-	movies, err := app.DB.AllMovies()
-	if err != nil {
-		app.errorJSON(w, err)
-		return
-	}
-
-	_ = app.writeJSON(w, http.StatusOK, movies)
+	app.listMovies(w, r)
 }
 
 func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
@@ -55,7 +44,7 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate user against database
-	user, err := app.DB.GetUserByEmail(requestPayload.Email)
+	user, err := app.DB.GetUserByEmail(normalizeEmail(requestPayload.Email))
 	if err != nil {
 		app.errorJSON(w, errors.New("invalid !!credentials"), http.StatusBadRequest)
 		return
@@ -69,19 +58,25 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create a jwt user
-	u := jwtUser{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
+	u, err := app.jwtUserFor(user)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
 	}
 
-	// generate tokens
-	tokens, err := app.auth.GenerateTokenPair(&u)
+	// generate tokens, starting a new refresh token family
+	tokens, err := app.auth.GenerateTokenPair(u, "")
 	if err != nil {
 		app.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
 
+	err = app.storeRefreshToken(user.ID, tokens)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	refreshCookie := app.auth.GetRefreshCookie(tokens.RefreshToken)
 	http.SetCookie(w, refreshCookie)
 
@@ -89,66 +84,138 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) refreshToken(w http.ResponseWriter, r *http.Request) {
-	for _, cookie := range r.Cookies() {
-		if cookie.Name == app.auth.CookieName {
-			claims := &Claims{}
-			refreshToken := cookie.Value
+	cookie, err := r.Cookie(app.auth.CookieName)
+	if err != nil {
+		app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
+	}
 
-			// parse the token to get the claims
-			_, err := jwt.ParseWithClaims(refreshToken, claims, func(t *jwt.Token) (any, error) {
-				return []byte(app.JWTSecret), nil
-			})
+	// parse the token to get the claims
+	claims, err := app.auth.ParseRefreshToken(cookie.Value)
+	if err != nil {
+		app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
+	}
 
-			if err != nil {
-				app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
-				return
-			}
+	stored, err := app.DB.GetRefreshToken(claims.ID)
+	if err != nil {
+		app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
+	}
 
-			// get the user id from the token claims
-			userId, err := strconv.Atoi(claims.Subject)
-			if err != nil {
-				app.errorJSON(w, errors.New("unknown user"), http.StatusUnauthorized)
-				return
-			}
+	if stored.RevokedAt != nil || stored.FamilyID != claims.FamilyID {
+		app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
+	}
 
-			user, err := app.DB.GetUserByID(userId)
-			if err != nil {
-				app.errorJSON(w, errors.New("unknown user"), http.StatusUnauthorized)
-				return
-			}
+	// A refresh token can only be used once. Seeing it again means it was copied,
+	// so revoke every token descended from the same login, including the one the
+	// legitimate client currently holds.
+	fresh, err := app.DB.MarkRefreshTokenUsed(stored.ID)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
 
-			u := jwtUser{
-				ID:        user.ID,
-				FirstName: user.FirstName,
-				LastName:  user.LastName,
-			}
+	if !fresh {
+		log.Printf("refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
 
-			tokenPairs, err := app.auth.GenerateTokenPair(&u)
-			if err != nil {
-				app.errorJSON(w, errors.New("error generating tokens"), http.StatusUnauthorized)
-				return
-			}
+		err = app.DB.RevokeRefreshTokenFamily(stored.FamilyID)
+		if err != nil {
+			app.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
 
-			http.SetCookie(w, app.auth.GetRefreshCookie(tokenPairs.RefreshToken))
+		http.SetCookie(w, app.auth.GetExpiredRefreshCookie())
+		app.errorJSON(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
+	}
 
-			app.writeJSON(w, http.StatusOK, tokenPairs)
-		}
+	user, err := app.DB.GetUserByID(stored.UserID)
+	if err != nil {
+		app.errorJSON(w, errors.New("unknown user"), http.StatusUnauthorized)
+		return
 	}
+
+	// roles are read again on every refresh, so role changes reach the
+	// access token within one token lifetime
+	u, err := app.jwtUserFor(user)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tokenPairs, err := app.auth.GenerateTokenPair(u, stored.FamilyID)
+	if err != nil {
+		app.errorJSON(w, errors.New("error generating tokens"), http.StatusUnauthorized)
+		return
+	}
+
+	err = app.storeRefreshToken(user.ID, tokenPairs)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, app.auth.GetRefreshCookie(tokenPairs.RefreshToken))
+
+	app.writeJSON(w, http.StatusOK, tokenPairs)
+}
+
+// jwtUserFor builds the token subject for user, including the roles embedded in the access token.
+func (app *application) jwtUserFor(user *models.User) (*jwtUser, error) {
+	roles, err := app.DB.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtUser{
+		ID:        user.ID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Roles:     roles,
+	}, nil
+}
+
+// storeRefreshToken records a newly issued refresh token so it can be rotated and revoked.
+func (app *application) storeRefreshToken(userID int, tokens TokenPairs) error {
+	return app.DB.InsertRefreshToken(models.RefreshToken{
+		ID:        tokens.RefreshTokenID,
+		FamilyID:  tokens.FamilyID,
+		UserID:    userID,
+		ExpiresAt: tokens.RefreshExpiry,
+	})
 }
 
 func (app *application) logout(w http.ResponseWriter, r *http.Request) {
+	// revoke the whole family server-side, so a copy of the cookie stops working too
+	cookie, err := r.Cookie(app.auth.CookieName)
+	if err == nil {
+		claims, err := app.auth.ParseRefreshToken(cookie.Value)
+		if err == nil {
+			err = app.DB.RevokeRefreshTokenFamily(claims.FamilyID)
+			if err != nil {
+				app.errorJSON(w, err, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
 	http.SetCookie(w, app.auth.GetExpiredRefreshCookie())
 	w.WriteHeader(http.StatusAccepted)
 }
 
-func (app *application) MovieCatalog(w http.ResponseWriter, r *http.Request) {
-	movies, err := app.DB.AllMovies()
-	if err != nil {
-		app.errorJSON(w, err)
-		return
-	}
+// jwks publishes the public keys tokens are verified with, so other services can
+// verify our tokens without sharing a secret.
+func (app *application) jwks(w http.ResponseWriter, r *http.Request) {
+	headers := http.Header{}
+	headers.Set("Cache-Control", "public, max-age=300")
 
-	_ = app.writeJSON(w, http.StatusOK, movies)
+	_ = app.writeJSON(w, http.StatusOK, app.auth.Keys.JWKS(), headers)
+}
+
+func (app *application) MovieCatalog(w http.ResponseWriter, r *http.Request) {
+	app.listMovies(w, r)
 }
 
 func (app *application) GetMovie(w http.ResponseWriter, r *http.Request) {
@@ -212,24 +279,25 @@ func (app *application) InsertMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// try to get an image
-	movie = app.getPoster(movie)
-
 	movie.CreatedAt = time.Now()
 	movie.UpdatedAt = time.Now()
 
-	newID, err := app.DB.InsertMovie(movie)
-	if err != nil {
-		app.errorJSON(w, err)
-		return
-	}
-	// now handler genres
-	err = app.DB.UpdateMovieGenres(newID, movie.GenresArray)
+	// the movie and its genres are stored together or not at all
+	err = app.DB.WithTx(func(tx repository.DatabaseRepo) error {
+		movie.ID, err = tx.InsertMovie(movie)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateMovieGenres(movie.ID, movie.GenresArray)
+	})
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
 
+	app.fetchPoster(movie)
+
 	resp := JSONResponse{
 		Error:   false,
 		Message: "movie updated",
@@ -238,51 +306,6 @@ func (app *application) InsertMovie(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusAccepted, resp)
 }
 
-func (app *application) getPoster(movie models.Movie) models.Movie {
-	type TheMovieDB struct {
-		Page    int `json:"page"`
-		Results []struct {
-			PosterPath string `json:"poster_path"`
-		} `json:"results"`
-		TotalPages int `json:"total_pages"`
-	}
-
-	client := &http.Client{}
-	theUrl := fmt.Sprintf("https://api.themoviedb.org/3/search/movie?api_key=%s", app.APIKey)
-
-	req, err := http.NewRequest("GET", theUrl+"&query="+url.QueryEscape(movie.Title), nil)
-	if err != nil {
-		log.Println(err)
-		return movie
-	}
-
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println(err)
-		return movie
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println(err)
-		return movie
-	}
-
-	var responseObject TheMovieDB
-
-	json.Unmarshal(bodyBytes, &responseObject)
-
-	if len(responseObject.Results) > 0 {
-		movie.Image = responseObject.Results[0].PosterPath
-	}
-
-	return movie
-}
-
 func (app *application) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 	var payload models.Movie
 
@@ -305,13 +328,14 @@ func (app *application) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 	movie.RunTime = payload.RunTime
 	movie.UpdatedAt = time.Now()
 
-	err = app.DB.UpdateMovie(*movie)
-	if err != nil {
-		app.errorJSON(w, err)
-		return
-	}
+	err = app.DB.WithTx(func(tx repository.DatabaseRepo) error {
+		err := tx.UpdateMovie(*movie)
+		if err != nil {
+			return err
+		}
 
-	err = app.DB.UpdateMovieGenres(movie.ID, payload.GenresArray)
+		return tx.UpdateMovieGenres(movie.ID, payload.GenresArray)
+	})
 	if err != nil {
 		app.errorJSON(w, err)
 		return
@@ -338,6 +362,11 @@ func (app *application) DeleteMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = app.posterCache.Delete(id)
+	if err != nil {
+		log.Printf("deleting poster of movie %d: %v", id, err)
+	}
+
 	resp := JSONResponse{
 		Error:   false,
 		Message: "movie deleted",
@@ -361,25 +390,3 @@ func (app *application) AllMoviesByGenre(w http.ResponseWriter, r *http.Request)
 
 	app.writeJSON(w, http.StatusOK, movies)
 }
-
-func (app *application) moviesGraphQL(w http.ResponseWriter, r *http.Request) {
-	movies, _ := app.DB.AllMovies()
-
-	q, _ := io.ReadAll(r.Body)
-	query := string(q)
-
-	g := graph.New(movies)
-
-	g.QueryString = query
-
-	resp, err := g.Query()
-	if err != nil {
-		app.errorJSON(w, err)
-		return
-	}
-
-	j, _ := json.MarshalIndent(resp, "", "\t")
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(j)
-}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/posters"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHome(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	rr := send(t, app, http.MethodGet, "/", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+
+	var payload struct{ Status string }
+	decode(t, rr, &payload)
+	if payload.Status != "active" {
+		t.Errorf("got status %q", payload.Status)
+	}
+}
+
+// moviePage is the body of /movies and /admin/movies.
+type moviePage struct {
+	Movies   []models.Movie
+	Metadata models.Metadata
+}
+
+func TestMovies(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	t.Run("all", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/movies", nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d", rr.Code)
+		}
+
+		var page moviePage
+		decode(t, rr, &page)
+		if movies := page.Movies; len(movies) != 2 || movies[0].Title != "Highlander" || movies[1].Title != "Raiders of the Lost Ark" {
+			t.Errorf("got %+v, want both movies ordered by title", movies)
+		}
+		if page.Metadata != (models.Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 1, TotalRecords: 2}) {
+			t.Errorf("got metadata %+v", page.Metadata)
+		}
+	})
+
+	t.Run("one with genres", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/movies/2", nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d", rr.Code)
+		}
+
+		var movie models.Movie
+		decode(t, rr, &movie)
+		if len(movie.Genres) != 2 || movie.Genres[0].Genre != "Comedy" || movie.Genres[1].Genre != "Drama" {
+			t.Errorf("got genres %+v, want Comedy and Drama", movie.Genres)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/movies/99", nil, "")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got status %d", rr.Code)
+		}
+	})
+
+	t.Run("bad id", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/movies/abc", nil, "")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got status %d", rr.Code)
+		}
+	})
+}
+
+func TestMovieListing(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	for _, m := range []struct {
+		movie  models.Movie
+		genres []int
+	}{
+		{models.Movie{Title: "Alien", ReleaseDate: date(1979, 5, 25), RunTime: 117, MPAARating: "R"}, []int{horrorID}},
+		{models.Movie{Title: "Airplane!", ReleaseDate: date(1980, 7, 2), RunTime: 88, MPAARating: "PG"}, []int{comedyID}},
+		{models.Movie{Title: "Toy Story", ReleaseDate: date(1995, 11, 22), RunTime: 81, MPAARating: "G"}, nil},
+	} {
+		id, err := db.InsertMovie(m.movie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateMovieGenres(id, m.genres); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viewer, _ := login(t, app, "viewer@example.com")
+
+	titles := func(page moviePage) string {
+		var titles []string
+		for _, m := range page.Movies {
+			titles = append(titles, m.Title)
+		}
+		return strings.Join(titles, ", ")
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"", "Airplane!, Alien, Highlander, Raiders of the Lost Ark, Toy Story"},
+		{"?sort=-release_date", "Toy Story, Highlander, Raiders of the Lost Ark, Airplane!, Alien"},
+		{"?sort=runtime", "Toy Story, Airplane!, Raiders of the Lost Ark, Highlander, Alien"},
+		{"?sort=mpaa_rating", "Toy Story, Airplane!, Raiders of the Lost Ark, Highlander, Alien"},
+		{"?sort=-mpaa_rating", "Highlander, Alien, Raiders of the Lost Ark, Airplane!, Toy Story"},
+		{"?rating=R,PG", "Airplane!, Alien, Highlander"},
+		{"?year_from=1980&year_to=1986", "Airplane!, Highlander, Raiders of the Lost Ark"},
+		{"?year_to=1980", "Airplane!, Alien"},
+		{"?genres=2,3", "Airplane!, Alien, Raiders of the Lost Ark"},
+		{"?genres=1&rating=PG13&sort=-title", "Raiders of the Lost Ark"},
+		{"?page=2&page_size=2", "Highlander, Raiders of the Lost Ark"},
+		{"?page=9", ""},
+	} {
+		for _, target := range []string{"/movies", "/admin/movies"} {
+			rr := send(t, app, http.MethodGet, target+tt.query, nil, viewer)
+			if rr.Code != http.StatusOK {
+				t.Errorf("%s%s: got status %d: %s", target, tt.query, rr.Code, rr.Body)
+				continue
+			}
+
+			var page moviePage
+			decode(t, rr, &page)
+			if got := titles(page); got != tt.want {
+				t.Errorf("%s%s: got %q, want %q", target, tt.query, got, tt.want)
+			}
+		}
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/movies?page=2&page_size=2&rating=", nil, "")
+
+		var page moviePage
+		decode(t, rr, &page)
+		if page.Metadata != (models.Metadata{CurrentPage: 2, PageSize: 2, FirstPage: 1, LastPage: 3, TotalRecords: 5}) {
+			t.Errorf("got metadata %+v", page.Metadata)
+		}
+
+		want := `</movies?page=1&page_size=2&rating=>; rel="first", ` +
+			`</movies?page=1&page_size=2&rating=>; rel="prev", ` +
+			`</movies?page=3&page_size=2&rating=>; rel="next", ` +
+			`</movies?page=3&page_size=2&rating=>; rel="last"`
+		if got := rr.Header().Get("Link"); got != want {
+			t.Errorf("got Link %s\nwant %s", got, want)
+		}
+
+		// a page past the end is empty but still reports the total and links back
+		rr = send(t, app, http.MethodGet, "/movies?page=9&page_size=2", nil, "")
+		page = moviePage{}
+		decode(t, rr, &page)
+		if page.Movies == nil || len(page.Movies) != 0 {
+			t.Errorf("past the last page: got movies %s", rr.Body)
+		}
+		if page.Metadata != (models.Metadata{CurrentPage: 9, PageSize: 2, FirstPage: 1, LastPage: 3, TotalRecords: 5}) {
+			t.Errorf("past the last page: got metadata %+v", page.Metadata)
+		}
+
+		want = `</movies?page=1&page_size=2>; rel="first", ` +
+			`</movies?page=3&page_size=2>; rel="prev", ` +
+			`</movies?page=3&page_size=2>; rel="last"`
+		if got := rr.Header().Get("Link"); got != want {
+			t.Errorf("past the last page: got Link %q, want %q", got, want)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"page=0",
+			"page=abc",
+			"page_size=101",
+			"sort=id",
+			"sort=title%3Bdrop%20table%20movies",
+			"rating=PG-13",
+			"year_from=1990&year_to=1980",
+			"year_from=-1",
+			"genres=1,x",
+		} {
+			rr := send(t, app, http.MethodGet, "/movies?"+query, nil, "")
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: got status %d", query, rr.Code)
+			}
+		}
+	})
+}
+
+func TestGenres(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	rr := send(t, app, http.MethodGet, "/genres", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+
+	var genres []models.Genre
+	decode(t, rr, &genres)
+	if len(genres) != 3 || genres[0].Genre != "Comedy" {
+		t.Errorf("got %+v, want three genres ordered by name", genres)
+	}
+
+	tests := []struct {
+		genre int
+		want  int
+	}{
+		{dramaID, 2},
+		{comedyID, 1},
+		{horrorID, 0},
+	}
+
+	for _, tt := range tests {
+		rr := send(t, app, http.MethodGet, "/movies/genres/"+strconv.Itoa(tt.genre), nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("genre %d: got status %d", tt.genre, rr.Code)
+		}
+
+		var movies []models.Movie
+		decode(t, rr, &movies)
+		if len(movies) != tt.want {
+			t.Errorf("genre %d: got %d movies, want %d", tt.genre, len(movies), tt.want)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	t.Run("valid", func(t *testing.T) {
+		token, cookie := login(t, app, "Admin@Example.com ")
+
+		if token == "" {
+			t.Error("no access token")
+		}
+		if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+			t.Errorf("refresh cookie is not HttpOnly, Secure and SameSite=Strict: %+v", cookie)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"wrong password", "admin@example.com", "not the password"},
+		{"unknown user", "nobody@example.com", testPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(t, app, http.MethodPost, "/authenticate", map[string]string{
+				"email":    tt.email,
+				"password": tt.password,
+			}, "")
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("got status %d", rr.Code)
+			}
+			if len(rr.Result().Cookies()) != 0 {
+				t.Error("failed login set a cookie")
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	t.Run("missing cookie", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/refresh", nil, "")
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d", rr.Code)
+		}
+	})
+
+	t.Run("rotation and reuse", func(t *testing.T) {
+		_, first := login(t, app, "admin@example.com")
+
+		rr := send(t, app, http.MethodGet, "/refresh", nil, "", first)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("refresh: got status %d: %s", rr.Code, rr.Body)
+		}
+
+		var tokens TokenPairs
+		decode(t, rr, &tokens)
+		second := refreshCookie(t, app, rr)
+
+		if second.Value == first.Value {
+			t.Fatal("refresh did not rotate the refresh token")
+		}
+
+		// the new access token carries the user's roles
+		rr = send(t, app, http.MethodGet, "/admin/users", nil, tokens.Token)
+		if rr.Code != http.StatusOK {
+			t.Errorf("refreshed access token: got status %d", rr.Code)
+		}
+
+		// replaying the first token revokes the whole family
+		rr = send(t, app, http.MethodGet, "/refresh", nil, "", first)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("reuse: got status %d", rr.Code)
+		}
+
+		rr = send(t, app, http.MethodGet, "/refresh", nil, "", second)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("token from a revoked family: got status %d", rr.Code)
+		}
+
+		for _, token := range db.RefreshTokens() {
+			if token.RevokedAt == nil {
+				t.Errorf("token %s was not revoked", token.ID)
+			}
+		}
+	})
+
+	t.Run("refresh token as access token", func(t *testing.T) {
+		_, cookie := login(t, app, "admin@example.com")
+
+		rr := send(t, app, http.MethodGet, "/admin/movies", nil, cookie.Value)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d", rr.Code)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	_, cookie := login(t, app, "viewer@example.com")
+
+	rr := send(t, app, http.MethodGet, "/logout", nil, "", cookie)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	if expired := refreshCookie(t, app, rr); expired.MaxAge >= 0 {
+		t.Errorf("logout did not expire the cookie: %+v", expired)
+	}
+
+	rr = send(t, app, http.MethodGet, "/refresh", nil, "", cookie)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: got status %d", rr.Code)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	rr := send(t, app, http.MethodGet, "/.well-known/jwks.json", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+
+	var set struct{ Keys []JWK }
+	decode(t, rr, &set)
+	if len(set.Keys) != 1 {
+		t.Errorf("got %d keys, want 1", len(set.Keys))
+	}
+	if cc := rr.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age") {
+		t.Errorf("got Cache-Control %q", cc)
+	}
+}
+
+func TestAdminMovies(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	editor, _ := login(t, app, "admin@example.com")
+	viewer, _ := login(t, app, "viewer@example.com")
+
+	t.Run("requires authentication", func(t *testing.T) {
+		for _, route := range []struct{ method, target string }{
+			{http.MethodGet, "/admin/movies"},
+			{http.MethodGet, "/admin/movies/1"},
+			{http.MethodPut, "/admin/movies/0"},
+			{http.MethodPatch, "/admin/movies/1"},
+			{http.MethodDelete, "/admin/movies/1"},
+		} {
+			rr := send(t, app, route.method, route.target, nil, "")
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s: got status %d", route.method, route.target, rr.Code)
+			}
+		}
+	})
+
+	t.Run("changes require the editor role", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/admin/movies", nil, viewer)
+		if rr.Code != http.StatusOK {
+			t.Errorf("catalog: got status %d", rr.Code)
+		}
+
+		for _, route := range []struct{ method, target string }{
+			{http.MethodPut, "/admin/movies/0"},
+			{http.MethodPatch, "/admin/movies/1"},
+			{http.MethodDelete, "/admin/movies/1"},
+		} {
+			rr := send(t, app, route.method, route.target, nil, viewer)
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("%s %s: got status %d", route.method, route.target, rr.Code)
+			}
+		}
+	})
+
+	t.Run("movie for edit", func(t *testing.T) {
+		rr := send(t, app, http.MethodGet, "/admin/movies/2", nil, editor)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d", rr.Code)
+		}
+
+		var payload struct {
+			Movie  models.Movie
+			Genres []models.Genre
+		}
+		decode(t, rr, &payload)
+
+		if len(payload.Movie.GenresArray) != 2 {
+			t.Errorf("got genres_array %v", payload.Movie.GenresArray)
+		}
+		if len(payload.Genres) != 3 {
+			t.Errorf("got %d genres to choose from, want 3", len(payload.Genres))
+		}
+	})
+
+	var newID int
+
+	t.Run("insert", func(t *testing.T) {
+		rr := send(t, app, http.MethodPut, "/admin/movies/0", map[string]any{
+			"title":        "The Thing",
+			"release_date": "1982-06-25T00:00:00Z",
+			"runtime":      109,
+			"mpaa_rating":  "R",
+			"description":  "Man is the warmest place to hide",
+			"genres_array": []int{horrorID},
+		}, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		movies, _ := db.AllMovies(horrorID)
+		if len(movies) != 1 || movies[0].Title != "The Thing" {
+			t.Fatalf("got horror movies %+v", movies)
+		}
+		newID = movies[0].ID
+	})
+
+	t.Run("poster", func(t *testing.T) {
+		// the poster is fetched after the response was sent
+		app.wg.Wait()
+
+		movie, err := db.OneMovie(newID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movie.Image != posterURL(newID) {
+			t.Fatalf("got image %q, want %q", movie.Image, posterURL(newID))
+		}
+
+		rr := send(t, app, http.MethodGet, movie.Image, nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d", rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "image/png" {
+			t.Errorf("got Content-Type %q", ct)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		rr := send(t, app, http.MethodPatch, "/admin/movies/"+strconv.Itoa(newID), map[string]any{
+			"id":           newID,
+			"title":        "The Thing (1982)",
+			"release_date": "1982-06-25T00:00:00Z",
+			"runtime":      109,
+			"mpaa_rating":  "R",
+			"description":  "Man is the warmest place to hide",
+			"genres_array": []int{horrorID, dramaID},
+		}, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+		}
+
+		movie, err := db.OneMovie(newID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movie.Title != "The Thing (1982)" || len(movie.Genres) != 2 {
+			t.Errorf("got %+v", movie)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rr := send(t, app, http.MethodDelete, "/admin/movies/"+strconv.Itoa(newID), nil, editor)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("got status %d", rr.Code)
+		}
+
+		if _, err := db.OneMovie(newID); err == nil {
+			t.Error("movie still exists")
+		}
+		if movies, _ := db.AllMovies(horrorID); len(movies) != 0 {
+			t.Errorf("genre link still exists: %+v", movies)
+		}
+
+		rr = send(t, app, http.MethodGet, posterURL(newID), nil, "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("poster of deleted movie: got status %d", rr.Code)
+		}
+	})
+}
+
+func TestMoviePosterProviderErrors(t *testing.T) {
+	app, db := newTestApplication(t)
+	app.posters = posters.Stub{Err: errors.New("provider is down")}
+
+	editor, _ := login(t, app, "admin@example.com")
+
+	rr := send(t, app, http.MethodPut, "/admin/movies/0", map[string]any{
+		"title":        "Alien",
+		"release_date": "1979-05-25T00:00:00Z",
+		"runtime":      117,
+		"mpaa_rating":  "R",
+	}, editor)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("a failing provider must not fail the insert: got status %d", rr.Code)
+	}
+
+	app.wg.Wait()
+
+	movies, _ := db.AllMovies()
+	for _, m := range movies {
+		if m.Title == "Alien" && m.Image != "" {
+			t.Errorf("got image %q", m.Image)
+		}
+	}
+
+	rr = send(t, app, http.MethodGet, "/images/1", nil, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("uncached poster: got status %d", rr.Code)
+	}
+}
+
+func TestAdminMoviesAreAtomic(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	editor, _ := login(t, app, "admin@example.com")
+
+	rr := send(t, app, http.MethodPut, "/admin/movies/0", map[string]any{
+		"title":        "Alien",
+		"release_date": "1979-05-25T00:00:00Z",
+		"runtime":      117,
+		"mpaa_rating":  "R",
+		"genres_array": []int{horrorID, 99},
+	}, editor)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("insert with an unknown genre: got status %d", rr.Code)
+	}
+
+	if movies, _ := db.AllMovies(); len(movies) != 2 {
+		t.Errorf("insert with an unknown genre left a movie behind: %+v", movies)
+	}
+
+	rr = send(t, app, http.MethodPatch, "/admin/movies/1", map[string]any{
+		"id":           highlanderID,
+		"title":        "Highlander II",
+		"release_date": "1991-03-15T00:00:00Z",
+		"runtime":      91,
+		"mpaa_rating":  "R",
+		"genres_array": []int{99},
+	}, editor)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("update with an unknown genre: got status %d", rr.Code)
+	}
+
+	movie, err := db.OneMovie(highlanderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Highlander" || len(movie.Genres) != 1 || movie.Genres[0].ID != dramaID {
+		t.Errorf("update with an unknown genre was partly applied: %+v", movie)
+	}
+}
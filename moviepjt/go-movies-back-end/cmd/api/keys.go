@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing or verifying tokens.
+const minRSABits = 2048
+
+// jwtKey is a public key tokens can be verified with, and the private key
+// for the key that signs new tokens.
+type jwtKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	Private crypto.Signer
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. During a rotation the previous key stays in the set as
+// a verify-only key until the tokens it signed have expired.
+type KeySet struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+	order   []string
+}
+
+// LoadKeySet reads a PEM private key used for signing and any number of PEM
+// public (or private) keys that are only used for verifying. Keys can be RSA
+// (RS256) or Ed25519 (EdDSA). Key IDs are the RFC 7638 thumbprint of each key.
+func LoadKeySet(signingKeyFile string, verifyKeyFiles []string) (*KeySet, error) {
+	pemBytes, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signing, err := parseJWTKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+	}
+
+	if signing.Private == nil {
+		return nil, fmt.Errorf("%s: signing key must be a private key", signingKeyFile)
+	}
+
+	ks := newKeySet(signing)
+
+	for _, file := range verifyKeyFiles {
+		pemBytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseJWTKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		// only the signing key ever signs, so drop any private half
+		key.Private = nil
+		ks.add(key)
+	}
+
+	return ks, nil
+}
+
+// GenerateKeySet creates a key set with a new random Ed25519 signing key. Tokens
+// signed with it stop verifying when the process exits, so it is only meant for
+// development.
+func GenerateKeySet() (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newJWTKey(public, private)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKeySet(key), nil
+}
+
+func newKeySet(signing *jwtKey) *KeySet {
+	ks := &KeySet{signing: signing, keys: make(map[string]*jwtKey)}
+	ks.add(signing)
+	return ks
+}
+
+func (ks *KeySet) add(key *jwtKey) {
+	if _, exists := ks.keys[key.ID]; exists {
+		return
+	}
+	ks.keys[key.ID] = key
+	ks.order = append(ks.order, key.ID)
+}
+
+// Sign signs claims with the current signing key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+
+	return token.SignedString(ks.signing.Private)
+}
+
+// Keyfunc looks up the verification key by the token's kid header. The alg header
+// must match the key's own algorithm, so a token can't pick how it is verified.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+
+	return key.Public, nil
+}
+
+func parseJWTKey(pemBytes []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return newJWTKey(&k.PublicKey, k)
+	case *rsa.PublicKey:
+		return newJWTKey(k, nil)
+	case ed25519.PrivateKey:
+		return newJWTKey(k.Public(), k)
+	case ed25519.PublicKey:
+		return newJWTKey(k, nil)
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", parsed)
+	}
+}
+
+func newJWTKey(public crypto.PublicKey, private crypto.Signer) (*jwtKey, error) {
+	key := &jwtKey{Public: public, Private: private}
+
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", public)
+	}
+
+	thumbprint, err := json.Marshal(publicJWK(key, true))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(thumbprint)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Crv string `json:"crv,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	X   string `json:"x,omitempty"`
+
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// publicJWK returns the JWK for a key. With thumbprint set, only the required
+// members are filled in, which marshal in the lexicographic order RFC 7638 hashes.
+func publicJWK(key *jwtKey, thumbprint bool) JWK {
+	var jwk JWK
+
+	switch k := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	}
+
+	if !thumbprint {
+		jwk.Alg = key.Method.Alg()
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+	}
+
+	return jwk
+}
+
+// JWKS returns every verification key, signing key first, as a JWK Set.
+func (ks *KeySet) JWKS() map[string][]JWK {
+	keys := make([]JWK, 0, len(ks.order))
+	for _, id := range ks.order {
+		keys = append(keys, publicJWK(ks.keys[id], false))
+	}
+
+	return map[string][]JWK{"keys": keys}
+}
@@ -1,27 +1,67 @@
 package main
 
 import (
+	"backend/internal/graph"
+	"backend/internal/posters"
 	"backend/internal/repository"
 	"backend/internal/repository/dbrepo"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
-const port = 5000
+// envFlags maps flags to the environment variables that set them when they
+// aren't given on the command line.
+var envFlags = map[string]string{
+	"port":                 "PORT",
+	"cors-allowed-origins": "CORS_ALLOWED_ORIGINS",
+	"read-timeout":         "READ_TIMEOUT",
+	"write-timeout":        "WRITE_TIMEOUT",
+	"idle-timeout":         "IDLE_TIMEOUT",
+	"shutdown-timeout":     "SHUTDOWN_TIMEOUT",
+}
 
 type application struct {
-	DSN          string
-	Domain       string
-	DB           repository.DatabaseRepo
-	auth         Auth
-	JWTSecret    string
-	JWTIssuer    string
-	JWTAudience  string
-	CookieDomain string
-	APIKey       string
+	Port          int
+	DSN           string
+	Domain        string
+	DB            repository.DatabaseRepo
+	graph         *graph.Graph
+	auth          Auth
+	JWTSigningKey string
+	JWTVerifyKeys []string
+	JWTIssuer     string
+	JWTAudience   string
+	CookieDomain  string
+	APIKey        string
+	GraphMaxDepth int
+	GraphMaxCost  int
+
+	PosterProvider string
+	TMDBURL        string
+	TMDBImageURL   string
+	PosterTimeout  time.Duration
+	PosterDir      string
+	posters        posters.Provider
+	posterCache    *posters.DiskCache
+
+	CORSOrigins     []string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// wg tracks background work, such as poster lookups
+	wg sync.WaitGroup
 }
 
 // entry point for app
@@ -30,13 +70,47 @@ func main() {
 	var app application
 
 	// read from command line e.g) flag
+	flag.IntVar(&app.Port, "port", 5000, "port to listen on ($PORT)")
+	app.CORSOrigins = []string{"https://api.wookseong.io"}
+	flag.Func("cors-allowed-origins", "comma separated origins allowed to call the API from a browser ($CORS_ALLOWED_ORIGINS, default https://api.wookseong.io)", func(val string) error {
+		origins, err := parseOrigins(val)
+		if err != nil {
+			return err
+		}
+		app.CORSOrigins = origins
+		return nil
+	})
+	flag.DurationVar(&app.ReadTimeout, "read-timeout", 10*time.Second, "longest time to read a request ($READ_TIMEOUT)")
+	flag.DurationVar(&app.WriteTimeout, "write-timeout", 30*time.Second, "longest time to write a response ($WRITE_TIMEOUT)")
+	flag.DurationVar(&app.IdleTimeout, "idle-timeout", time.Minute, "how long idle keep-alive connections stay open ($IDLE_TIMEOUT)")
+	flag.DurationVar(&app.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "how long requests and background work get to finish on shutdown ($SHUTDOWN_TIMEOUT)")
 	flag.StringVar(&app.DSN, "dsn", "host=localhost port=5432 user=postgres password=postgres dbname=movies sslmode=disable timezone=UTC connect_timeout=5", "Postgres connection string")
-	flag.StringVar(&app.JWTSecret, "jwt-secret", "verysecret", "signing secret")
+	flag.StringVar(&app.JWTSigningKey, "jwt-signing-key", "", "PEM file with the RSA or Ed25519 private key tokens are signed with")
+	flag.Func("jwt-verify-keys", "comma separated PEM files with previous public keys still accepted during rotation", func(val string) error {
+		for _, file := range strings.Split(val, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				app.JWTVerifyKeys = append(app.JWTVerifyKeys, file)
+			}
+		}
+		return nil
+	})
 	flag.StringVar(&app.JWTIssuer, "jwt-issuer", "example.com", "signing issuer")
 	flag.StringVar(&app.JWTAudience, "jwt-audience", "example.com", "signing audience")
 	flag.StringVar(&app.CookieDomain, "cookie-domain", "localhost", "cookie domain")
 	flag.StringVar(&app.Domain, "domain", "localhost", "domain")
-	flag.StringVar(&app.APIKey, "api-key", "097c59bd534df586373e3cf6ad9603d9", "tmdb api key")
+	flag.IntVar(&app.GraphMaxDepth, "graphql-max-depth", 15, "deepest field nesting a GraphQL operation may select (0 for no limit)")
+	flag.IntVar(&app.GraphMaxCost, "graphql-max-complexity", 500, "most fields a GraphQL operation may select (0 for no limit)")
+	flag.StringVar(&app.APIKey, "api-key", os.Getenv("TMDB_API_KEY"), "tmdb api key (defaults to $TMDB_API_KEY)")
+	flag.StringVar(&app.PosterProvider, "poster-provider", "tmdb", "where posters come from: tmdb, stub or none")
+	flag.StringVar(&app.TMDBURL, "tmdb-url", "https://api.themoviedb.org/3", "tmdb api base url")
+	flag.StringVar(&app.TMDBImageURL, "tmdb-image-url", "https://image.tmdb.org/t/p/w200", "tmdb poster image base url")
+	flag.DurationVar(&app.PosterTimeout, "poster-timeout", 10*time.Second, "timeout of each request to the poster provider")
+	flag.StringVar(&app.PosterDir, "poster-dir", "posters", "directory posters are cached in")
+
+	err := setFlagsFromEnv(flag.CommandLine)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	flag.Parse()
 
@@ -47,12 +121,34 @@ func main() {
 	}
 
 	app.DB = &dbrepo.PostgresDBRepo{DB: conn}
-	defer app.DB.Connection().Close()
+
+	app.graph, err = graph.New(app.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.graph.Inserted = app.fetchPoster
+	app.graph.MaxDepth = app.GraphMaxDepth
+	app.graph.MaxComplexity = app.GraphMaxCost
+
+	app.posters, err = app.newPosterProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app.posterCache, err = posters.NewDiskCache(app.PosterDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := app.loadKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	app.auth = Auth{
 		Issuer:        app.JWTIssuer,
 		Audience:      app.JWTAudience,
-		Secret:        app.JWTSecret,
+		Keys:          keys,
 		TokenExpiry:   time.Minute * 15,
 		RefreshExpiry: time.Hour * 24,
 		CookiePath:    "/",
@@ -61,10 +157,49 @@ func main() {
 	}
 
 	// start a web server
-	log.Println("Starting app on port:", port)
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", app.Port))
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), app.routes())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = app.serve(ctx, ln)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// setFlagsFromEnv sets the flags in envFlags from the environment. It runs
+// before the command line is parsed, so flags given there still win.
+func setFlagsFromEnv(fs *flag.FlagSet) error {
+	for name, key := range envFlags {
+		val, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		err := fs.Set(name, val)
+		if err != nil {
+			return fmt.Errorf("$%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// loadKeys loads the token signing keys. Without a signing key file it generates
+// a throwaway key, so tokens stop working whenever the server restarts.
+func (app *application) loadKeys() (*KeySet, error) {
+	if app.JWTSigningKey == "" {
+		if len(app.JWTVerifyKeys) > 0 {
+			return nil, errors.New("-jwt-verify-keys requires -jwt-signing-key")
+		}
+
+		log.Println("No -jwt-signing-key given, using a temporary key; tokens will not survive a restart")
+		return GenerateKeySet()
+	}
+
+	return LoadKeySet(app.JWTSigningKey, app.JWTVerifyKeys)
+}
@@ -1,15 +1,45 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"net/http"
+)
 
+type contextKey string
+
+// claimsContextKey is the request context key authRequired stores the access token claims under.
+const claimsContextKey = contextKey("claims")
+
+// claimsFromContext returns the access token claims of a request that passed authRequired.
+func claimsFromContext(r *http.Request) *Claims {
+	claims, ok := r.Context().Value(claimsContextKey).(*Claims)
+	if !ok {
+		panic("missing claims in request context")
+	}
+	return claims
+}
+
+// enableCORS lets the origins in app.CORSOrigins call the API from a browser,
+// with credentials so the refresh cookie is sent along.
 func (app *application) enableCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "https://api.wookseong.io")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && permittedValue(origin, app.CORSOrigins...)
+
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", "Link")
+		}
 
 		if r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, X-CSRF-Token, Authorization")
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, X-CSRF-Token, Authorization")
+			}
 			return
 		} else {
 			h.ServeHTTP(w, r)
@@ -19,12 +49,28 @@ func (app *application) enableCORS(h http.Handler) http.Handler {
 
 func (app *application) authRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _, err := app.auth.GetTokenFromHeaderAndVerify(w, r)
+		_, claims, err := app.auth.GetTokenFromHeaderAndVerify(w, r)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// requireRole only lets requests through whose access token carries role. It must
+// run after authRequired.
+func (app *application) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !claimsFromContext(r).HasRole(role) {
+				app.errorJSON(w, errors.New("forbidden"), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
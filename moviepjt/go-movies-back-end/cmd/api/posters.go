@@ -0,0 +1,108 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/posters"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// posterFetchTimeout bounds the whole background lookup of one poster.
+const posterFetchTimeout = 30 * time.Second
+
+// newPosterProvider returns the poster provider chosen with -poster-provider, or
+// nil when posters are turned off.
+func (app *application) newPosterProvider() (posters.Provider, error) {
+	switch app.PosterProvider {
+	case "tmdb":
+		if app.APIKey == "" {
+			log.Println("No TMDB api key given, posters are turned off")
+			return nil, nil
+		}
+		return posters.NewTMDB(app.APIKey, app.TMDBURL, app.TMDBImageURL, app.PosterTimeout), nil
+	case "stub":
+		return posters.Stub{}, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown poster provider %q", app.PosterProvider)
+	}
+}
+
+// posterURL is where the API serves the cached poster of a movie.
+func posterURL(movieID int) string {
+	return "/images/" + strconv.Itoa(movieID)
+}
+
+// fetchPoster looks up the poster of a newly inserted movie in the background, so
+// the request that added the movie doesn't wait on the provider. Once the poster
+// is cached the movie's image is pointed at our own copy.
+func (app *application) fetchPoster(movie models.Movie) {
+	if app.posters == nil {
+		return
+	}
+
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("fetching poster for movie %d: panic: %v", movie.ID, err)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), posterFetchTimeout)
+		defer cancel()
+
+		poster, err := app.posters.Poster(ctx, movie.Title)
+		if err != nil {
+			if errors.Is(err, posters.ErrNotFound) {
+				log.Printf("no poster found for movie %d (%q)", movie.ID, movie.Title)
+				return
+			}
+			log.Printf("fetching poster for movie %d: %v", movie.ID, err)
+			return
+		}
+
+		err = app.posterCache.Save(movie.ID, poster)
+		if err != nil {
+			log.Printf("caching poster for movie %d: %v", movie.ID, err)
+			return
+		}
+
+		err = app.DB.UpdateMovieImage(movie.ID, posterURL(movie.ID))
+		if err != nil {
+			log.Printf("setting poster for movie %d: %v", movie.ID, err)
+		}
+	}()
+}
+
+// moviePoster serves the cached poster of a movie.
+func (app *application) moviePoster(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("poster not found"), http.StatusNotFound)
+		return
+	}
+
+	path, err := app.posterCache.Path(id)
+	if err != nil {
+		if errors.Is(err, posters.ErrNotFound) {
+			app.errorJSON(w, errors.New("poster not found"), http.StatusNotFound)
+			return
+		}
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFile(w, r, path)
+}
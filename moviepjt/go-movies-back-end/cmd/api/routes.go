@@ -14,26 +14,42 @@ func (app *application) routes() http.Handler {
 	mux.Use(app.enableCORS)
 
 	mux.Get("/", app.Home)
+	mux.Get("/healthz", app.healthz)
+	mux.Get("/.well-known/jwks.json", app.jwks)
 
+	mux.Post("/register", app.register)
 	mux.Post("/authenticate", app.authenticate)
 	mux.Get("/refresh", app.refreshToken)
 	mux.Get("/logout", app.logout)
+	mux.With(app.authRequired).Put("/account/password", app.changePassword)
 
 	mux.Get("/movies", app.AllMovies)
 	mux.Get("/movies/{id}", app.GetMovie)
+	mux.Get("/images/{id}", app.moviePoster)
 
 	mux.Get("/genres", app.AllGenres)
 	mux.Get("/movies/genres/{id}", app.AllMoviesByGenre)
 
+	mux.Get("/graph", app.moviesGraphQL)
 	mux.Post("/graph", app.moviesGraphQL)
 
 	mux.Route("/admin", func(r chi.Router) {
 		r.Use(app.authRequired)
 		r.Get("/movies", app.MovieCatalog)
 		r.Get("/movies/{id}", app.MovieForEdit)
-		r.Put("/movies/0", app.InsertMovie)
-		r.Patch("/movies/{id}", app.UpdateMovie)
-		r.Delete("/movies/{id}", app.DeleteMovie)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.requireRole("editor"))
+			r.Put("/movies/0", app.InsertMovie)
+			r.Patch("/movies/{id}", app.UpdateMovie)
+			r.Delete("/movies/{id}", app.DeleteMovie)
+
+			r.Post("/genres", app.InsertGenre)
+			r.Patch("/genres/{id}", app.UpdateGenre)
+			r.Delete("/genres/{id}", app.DeleteGenre)
+		})
+
+		r.With(app.requireRole("admin")).Get("/users", app.AllUsers)
 	})
 
 	return mux
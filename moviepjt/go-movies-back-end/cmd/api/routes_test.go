@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestRoutes fails when a route is added to or removed from routes() without
+// updating this list, as a reminder to cover the route in the handler tests.
+func TestRoutes(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	want := map[string]bool{
+		"GET /":                      true,
+		"GET /healthz":               true,
+		"GET /.well-known/jwks.json": true,
+		"POST /register":             true,
+		"POST /authenticate":         true,
+		"GET /refresh":               true,
+		"GET /logout":                true,
+		"PUT /account/password":      true,
+		"GET /movies":                true,
+		"GET /movies/{id}":           true,
+		"GET /images/{id}":           true,
+		"GET /genres":                true,
+		"GET /movies/genres/{id}":    true,
+		"GET /graph":                 true,
+		"POST /graph":                true,
+		"GET /admin/movies":          true,
+		"GET /admin/movies/{id}":     true,
+		"PUT /admin/movies/0":        true,
+		"PATCH /admin/movies/{id}":   true,
+		"DELETE /admin/movies/{id}":  true,
+		"POST /admin/genres":         true,
+		"PATCH /admin/genres/{id}":   true,
+		"DELETE /admin/genres/{id}":  true,
+		"GET /admin/users":           true,
+	}
+
+	mux, ok := app.routes().(chi.Routes)
+	if !ok {
+		t.Fatalf("routes() returned %T, not a chi router", app.routes())
+	}
+
+	got := make(map[string]bool)
+
+	err := chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		got[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range want {
+		if !got[route] {
+			t.Errorf("route %s is not registered", route)
+		}
+	}
+	for route := range got {
+		if !want[route] {
+			t.Errorf("route %s has no test", route)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// healthCheckTimeout bounds the database ping of /healthz.
+const healthCheckTimeout = 2 * time.Second
+
+// serve runs the API on ln until ctx is done, then shuts down gracefully: the
+// listener is closed, requests in flight and background work such as poster
+// lookups get until ShutdownTimeout to finish, and the database is closed.
+func (app *application) serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:      app.routes(),
+		ReadTimeout:  app.ReadTimeout,
+		WriteTimeout: app.WriteTimeout,
+		IdleTimeout:  app.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	log.Println("Starting app on", ln.Addr())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.ShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		// drop the requests that didn't finish in time
+		srv.Close()
+		err = fmt.Errorf("shutting down server: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		app.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		log.Println("Background work did not finish before the shutdown deadline")
+	}
+
+	if conn := app.DB.Connection(); conn != nil {
+		closeErr := conn.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("closing database: %w", closeErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	log.Println("Stopped server")
+	return nil
+}
+
+// healthz reports whether the API can serve requests, which needs the database.
+func (app *application) healthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	var payload = struct {
+		Status   string `json:"status"`
+		Database string `json:"database"`
+	}{
+		Status:   "available",
+		Database: "up",
+	}
+	status := http.StatusOK
+
+	err := app.DB.Ping(ctx)
+	if err != nil {
+		log.Println("health check: database:", err)
+
+		payload.Status = "unavailable"
+		payload.Database = "down"
+		status = http.StatusServiceUnavailable
+	}
+
+	headers := http.Header{}
+	headers.Set("Cache-Control", "no-store")
+
+	_ = app.writeJSON(w, status, payload, headers)
+}
+
+// parseOrigins splits a comma separated list of origins allowed to call the API
+// from a browser. Each must be a scheme and host, with an optional port.
+func parseOrigins(val string) ([]string, error) {
+	var origins []string
+
+	for _, origin := range strings.Split(val, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+
+		// credentialed requests can't use a wildcard origin
+		if origin == "*" {
+			return nil, errors.New("origins must be listed explicitly, not as *")
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid origin %q, want scheme://host[:port]", origin)
+		}
+
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+
+	return origins, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"backend/internal/repository/testrepo"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// unreachableDB is a database driver that never connects, for checking that
+// serve closes the pool.
+type unreachableDB struct{}
+
+func (unreachableDB) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("no database in tests")
+}
+func (unreachableDB) Driver() driver.Driver { return nil }
+
+// poolRepo is a test repository that hands out a connection pool.
+type poolRepo struct {
+	*testrepo.TestDBRepo
+	pool *sql.DB
+}
+
+func (r poolRepo) Connection() *sql.DB {
+	return r.pool
+}
+
+func TestServe(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	pool := sql.OpenDB(unreachableDB{})
+	app.DB = poolRepo{TestDBRepo: db, pool: pool}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- app.serve(ctx, ln)
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("healthz: got status %d", resp.StatusCode)
+	}
+
+	var finished atomic.Bool
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(app.ShutdownTimeout):
+		t.Fatal("serve did not return after shutdown")
+	}
+
+	if !finished.Load() {
+		t.Error("serve returned before background work finished")
+	}
+
+	if err := pool.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("database pool: got %v, want it closed", err)
+	}
+
+	if _, err := http.Get("http://" + ln.Addr().String() + "/healthz"); err == nil {
+		t.Error("server still accepts requests after shutdown")
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	var payload struct{ Status, Database string }
+
+	rr := send(t, app, http.MethodGet, "/healthz", nil, "")
+	decode(t, rr, &payload)
+	if rr.Code != http.StatusOK || payload.Status != "available" || payload.Database != "up" {
+		t.Errorf("got status %d: %s", rr.Code, rr.Body)
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("got Cache-Control %q", cc)
+	}
+
+	db.SetPingError(errors.New("connection refused"))
+
+	rr = send(t, app, http.MethodGet, "/healthz", nil, "")
+	decode(t, rr, &payload)
+	if rr.Code != http.StatusServiceUnavailable || payload.Status != "unavailable" || payload.Database != "down" {
+		t.Errorf("database down: got status %d: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/movies", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request(http.MethodGet, "http://localhost:3000")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("allowed origin: got Access-Control-Allow-Origin %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("allowed origin: got Access-Control-Allow-Credentials %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("got Vary %q", got)
+	}
+
+	rr = request(http.MethodOptions, "http://localhost:3000")
+	if rr.Code != http.StatusOK || rr.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("preflight: got status %d and headers %v", rr.Code, rr.Header())
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		rr = request(method, "https://evil.example")
+		for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Methods"} {
+			if got := rr.Header().Get(h); got != "" {
+				t.Errorf("%s from another origin: got %s %q", method, h, got)
+			}
+		}
+	}
+}
+
+func TestParseOrigins(t *testing.T) {
+	origins, err := parseOrigins(" https://movies.example.com, http://localhost:3000/ ,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origins) != 2 || origins[0] != "https://movies.example.com" || origins[1] != "http://localhost:3000" {
+		t.Errorf("got %q", origins)
+	}
+
+	for _, val := range []string{
+		"*",
+		"movies.example.com",
+		"https://movies.example.com/app",
+		"https://user@movies.example.com",
+		"https://movies.example.com?x=1",
+	} {
+		if _, err := parseOrigins(val); err == nil {
+			t.Errorf("%q: got no error", val)
+		}
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	var (
+		port    int
+		timeout time.Duration
+	)
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	for name := range envFlags {
+		switch name {
+		case "port":
+			fs.IntVar(&port, name, 5000, "")
+		case "read-timeout":
+			fs.DurationVar(&timeout, name, time.Second, "")
+		default:
+			fs.String(name, "", "")
+		}
+	}
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("READ_TIMEOUT", "5s")
+
+	err := setFlagsFromEnv(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 8080 || timeout != 5*time.Second {
+		t.Errorf("from the environment: got port %d and timeout %s", port, timeout)
+	}
+
+	err = fs.Parse([]string{"-port", "9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 9000 {
+		t.Errorf("the command line should win: got port %d", port)
+	}
+
+	t.Setenv("READ_TIMEOUT", "soon")
+	if err := setFlagsFromEnv(fs); err == nil {
+		t.Error("invalid $READ_TIMEOUT: got no error")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"backend/internal/graph"
+	"backend/internal/models"
+	"backend/internal/posters"
+	"backend/internal/repository/testrepo"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery"
+
+// fixture ids, in the order newTestApplication creates the rows
+const (
+	dramaID  = 1
+	comedyID = 2
+	horrorID = 3
+
+	highlanderID = 1
+	raidersID    = 2
+
+	adminID  = 1
+	viewerID = 2
+)
+
+// newTestApplication returns an application backed by an in-memory repository
+// holding three genres, two movies and two users: admin@example.com, who has the
+// admin and editor roles, and viewer@example.com, who has none. Both use testPassword.
+// Posters come from the offline stub provider and are cached in a temporary directory.
+func newTestApplication(t *testing.T) (*application, *testrepo.TestDBRepo) {
+	t.Helper()
+
+	db := testrepo.New()
+
+	db.AddGenre("Drama")
+	db.AddGenre("Comedy")
+	db.AddGenre("Horror")
+
+	for _, m := range []struct {
+		movie  models.Movie
+		genres []int
+	}{
+		{models.Movie{Title: "Highlander", ReleaseDate: date(1986, 3, 7), RunTime: 116, MPAARating: "R", Description: "There can be only one"}, []int{dramaID}},
+		{models.Movie{Title: "Raiders of the Lost Ark", ReleaseDate: date(1981, 6, 12), RunTime: 115, MPAARating: "PG13", Description: "Indy"}, []int{dramaID, comedyID}},
+	} {
+		id, err := db.InsertMovie(m.movie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateMovieGenres(id, m.genres); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the lowest bcrypt cost keeps the suite fast
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []models.User{
+		{FirstName: "Admin", LastName: "User", Email: "admin@example.com", Password: string(hash)},
+		{FirstName: "Viewer", LastName: "User", Email: "viewer@example.com", Password: string(hash)},
+	} {
+		if _, err := db.InsertUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.SetUserRoles(adminID, "admin", "editor")
+
+	keys, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		DB: db,
+		auth: Auth{
+			Issuer:        "example.com",
+			Audience:      "example.com",
+			Keys:          keys,
+			TokenExpiry:   time.Minute * 15,
+			RefreshExpiry: time.Hour * 24,
+			CookiePath:    "/",
+			CookieName:    "__Host-refresh_token",
+			CookieDomain:  "localhost",
+		},
+		CORSOrigins:     []string{"http://localhost:3000"},
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	app.posters = posters.Stub{}
+	app.posterCache, err = posters.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// let poster lookups finish before the directory is removed
+	t.Cleanup(app.wg.Wait)
+
+	app.graph, err = graph.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.graph.Inserted = app.fetchPoster
+	app.graph.MaxDepth = 15
+	app.graph.MaxComplexity = 500
+
+	return app, db
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// send runs a request through the application's routes. body is encoded as JSON
+// unless it is nil, and token, when set, is sent as a bearer token.
+func send(t *testing.T, app *application, method, target string, body any, token string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, target, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	return rr
+}
+
+// login signs in and returns the access token and the refresh cookie.
+func login(t *testing.T, app *application, email string) (string, *http.Cookie) {
+	t.Helper()
+
+	rr := send(t, app, http.MethodPost, "/authenticate", map[string]string{
+		"email":    email,
+		"password": testPassword,
+	}, "")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("login as %s: got status %d: %s", email, rr.Code, rr.Body)
+	}
+
+	var tokens TokenPairs
+	decode(t, rr, &tokens)
+
+	return tokens.Token, refreshCookie(t, app, rr)
+}
+
+// refreshCookie returns the refresh cookie a response set.
+func refreshCookie(t *testing.T, app *application, rr *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == app.auth.CookieName {
+			return c
+		}
+	}
+
+	t.Fatalf("response did not set the %s cookie", app.auth.CookieName)
+	return nil
+}
+
+func decode(t *testing.T, rr *httptest.ResponseRecorder, dst any) {
+	t.Helper()
+
+	err := json.Unmarshal(rr.Body.Bytes(), dst)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", rr.Body, err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// bcrypt only looks at the first 72 bytes of a password
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+var errEmailTaken = errors.New("a user with this email address already exists")
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("password must be at least 8 bytes long")
+	}
+	if len(password) > maxPasswordLength {
+		return errors.New("password must not be more than 72 bytes long")
+	}
+	return nil
+}
+
+// normalizeEmail lowercases an address so uniqueness doesn't depend on case.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (app *application) register(w http.ResponseWriter, r *http.Request) {
+	var requestPayload struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	user := models.User{
+		FirstName: strings.TrimSpace(requestPayload.FirstName),
+		LastName:  strings.TrimSpace(requestPayload.LastName),
+		Email:     normalizeEmail(requestPayload.Email),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if user.FirstName == "" || user.LastName == "" {
+		app.errorJSON(w, errors.New("first and last name are required"), http.StatusUnprocessableEntity)
+		return
+	}
+
+	addr, err := mail.ParseAddress(user.Email)
+	if err != nil || addr.Address != user.Email {
+		app.errorJSON(w, errors.New("invalid email address"), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validatePassword(requestPayload.Password)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = app.DB.GetUserByEmail(user.Email)
+	switch {
+	case err == nil:
+		app.errorJSON(w, errEmailTaken, http.StatusConflict)
+		return
+	case !errors.Is(err, sql.ErrNoRows):
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	err = user.SetPassword(requestPayload.Password)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	user.ID, err = app.DB.InsertUser(user)
+	if err != nil {
+		// two registrations for the same address can both pass the check above
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			app.errorJSON(w, errEmailTaken, http.StatusConflict)
+			return
+		}
+
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := JSONResponse{
+		Error:   false,
+		Message: "user created",
+		Data:    user,
+	}
+
+	app.writeJSON(w, http.StatusCreated, resp)
+}
+
+// changePassword replaces the signed in user's password. Every refresh token the
+// user holds is revoked, so all sessions, including this one, must sign in again.
+func (app *application) changePassword(w http.ResponseWriter, r *http.Request) {
+	var requestPayload struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(claimsFromContext(r).Subject)
+	if err != nil {
+		app.errorJSON(w, errors.New("unknown user"), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := app.DB.GetUserByID(userID)
+	if err != nil {
+		app.errorJSON(w, errors.New("unknown user"), http.StatusUnauthorized)
+		return
+	}
+
+	valid, err := user.PasswordMatches(requestPayload.CurrentPassword)
+	if err != nil || !valid {
+		app.errorJSON(w, errors.New("invalid credentials"), http.StatusBadRequest)
+		return
+	}
+
+	err = validatePassword(requestPayload.NewPassword)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = user.SetPassword(requestPayload.NewPassword)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	user.UpdatedAt = time.Now()
+
+	err = app.DB.UpdateUser(*user)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.RevokeUserRefreshTokens(user.ID)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, app.auth.GetExpiredRefreshCookie())
+
+	resp := JSONResponse{
+		Error:   false,
+		Message: "password changed",
+	}
+
+	app.writeJSON(w, http.StatusAccepted, resp)
+}
+
+func (app *application) AllUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := app.DB.ListUsers()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	_ = app.writeJSON(w, http.StatusOK, users)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"backend/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	app, db := newTestApplication(t)
+
+	valid := map[string]string{
+		"first_name": "New",
+		"last_name":  "User",
+		"email":      " New@Example.com",
+		"password":   testPassword,
+	}
+
+	rr := send(t, app, http.MethodPost, "/register", valid, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), "password") {
+		t.Errorf("response leaks the password hash: %s", rr.Body)
+	}
+
+	user, err := db.GetUserByEmail("new@example.com")
+	if err != nil {
+		t.Fatalf("registered user not stored under the normalized email: %v", err)
+	}
+	if ok, _ := user.PasswordMatches(testPassword); !ok {
+		t.Error("stored password does not match")
+	}
+
+	tests := []struct {
+		name   string
+		change func(map[string]string)
+		want   int
+	}{
+		{"duplicate email", func(p map[string]string) { p["email"] = "NEW@example.com" }, http.StatusConflict},
+		{"short password", func(p map[string]string) { p["email"] = "a@example.com"; p["password"] = "short" }, http.StatusUnprocessableEntity},
+		{"invalid email", func(p map[string]string) { p["email"] = "not an email" }, http.StatusUnprocessableEntity},
+		{"missing name", func(p map[string]string) { p["email"] = "b@example.com"; p["first_name"] = " " }, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := make(map[string]string)
+			for k, v := range valid {
+				payload[k] = v
+			}
+			tt.change(payload)
+
+			rr := send(t, app, http.MethodPost, "/register", payload, "")
+			if rr.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", rr.Code, tt.want, rr.Body)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	token, cookie := login(t, app, "viewer@example.com")
+
+	rr := send(t, app, http.MethodPut, "/account/password", map[string]string{
+		"current_password": testPassword,
+		"new_password":     "a brand new password",
+	}, "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: got status %d", rr.Code)
+	}
+
+	rr = send(t, app, http.MethodPut, "/account/password", map[string]string{
+		"current_password": "wrong password",
+		"new_password":     "a brand new password",
+	}, token)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("wrong current password: got status %d", rr.Code)
+	}
+
+	rr = send(t, app, http.MethodPut, "/account/password", map[string]string{
+		"current_password": testPassword,
+		"new_password":     "a brand new password",
+	}, token)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = send(t, app, http.MethodGet, "/refresh", nil, "", cookie)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with a session from before the change: got status %d", rr.Code)
+	}
+
+	rr = send(t, app, http.MethodPost, "/authenticate", map[string]string{
+		"email":    "viewer@example.com",
+		"password": testPassword,
+	}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("login with the old password: got status %d", rr.Code)
+	}
+
+	rr = send(t, app, http.MethodPost, "/authenticate", map[string]string{
+		"email":    "viewer@example.com",
+		"password": "a brand new password",
+	}, "")
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("login with the new password: got status %d", rr.Code)
+	}
+}
+
+func TestAllUsers(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	admin, _ := login(t, app, "admin@example.com")
+	viewer, _ := login(t, app, "viewer@example.com")
+
+	rr := send(t, app, http.MethodGet, "/admin/users", nil, viewer)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("viewer: got status %d", rr.Code)
+	}
+
+	rr = send(t, app, http.MethodGet, "/admin/users", nil, admin)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("admin: got status %d", rr.Code)
+	}
+
+	var users []models.User
+	decode(t, rr, &users)
+	if len(users) != 2 || users[0].ID != adminID || users[1].ID != viewerID {
+		t.Errorf("got %+v", users)
+	}
+	if strings.Contains(rr.Body.String(), "$2a$") {
+		t.Errorf("response leaks password hashes: %s", rr.Body)
+	}
+}
@@ -2,21 +2,162 @@ package graph
 
 import (
 	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
 )
 
+// dateLayout is the format release dates are accepted in by the mutations.
+const dateLayout = "2006-01-02"
+
+// editorRole is the role a caller needs to run mutations.
+const editorRole = "editor"
+
+var (
+	errUnauthenticated = errors.New("authentication required")
+	errForbidden       = errors.New("forbidden")
+	errMovieNotFound   = errors.New("movie not found")
+)
+
+// ErrMutationNotAllowed is returned by Do when a mutation arrives in a request
+// that may only read, such as a GET.
+var ErrMutationNotAllowed = errors.New("mutations are only accepted in POST requests")
+
+// Graph holds a schema built once at startup. Resolvers read from and write to
+// the repository directly, so every query sees the current data.
 type Graph struct {
-	Movies      []*models.Movie
-	QueryString string
-	Config      graphql.ScalarConfig
-	fields      graphql.Fields
-	movieType   *graphql.Object
+	DB        repository.DatabaseRepo
+	schema    graphql.Schema
+	persisted *queryStore
+
+	// Inserted, when set, is called with every movie added by a mutation.
+	Inserted func(movie models.Movie)
+
+	// MaxDepth and MaxComplexity reject operations that nest fields deeper or
+	// select more fields than this before they run. Zero means no limit.
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// Request is a GraphQL-over-HTTP request.
+type Request struct {
+	Query         string                     `json:"query"`
+	Variables     map[string]interface{}     `json:"variables"`
+	OperationName string                     `json:"operationName"`
+	Extensions    map[string]json.RawMessage `json:"extensions"`
+}
+
+// Caller is the authenticated user a request is made on behalf of.
+type Caller struct {
+	UserID int
+	Roles  []string
+}
+
+func (c *Caller) hasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey string
+
+const (
+	callerContextKey = contextKey("caller")
+	loaderContextKey = contextKey("genreLoader")
+)
+
+// WithCaller returns a copy of ctx that carries the authenticated caller.
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey, caller)
+}
+
+func callerFromContext(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerContextKey).(*Caller)
+	return caller
+}
+
+// requireEditor returns an error unless the caller is signed in with the editor role.
+func requireEditor(ctx context.Context) error {
+	caller := callerFromContext(ctx)
+	if caller == nil {
+		return errUnauthenticated
+	}
+	if !caller.hasRole(editorRole) {
+		return errForbidden
+	}
+	return nil
+}
+
+// genreLoader collects the ids of the movies whose genres were asked for while a
+// selection set is resolved, and fetches them all with one query the first time
+// any of the results is needed.
+type genreLoader struct {
+	db      repository.DatabaseRepo
+	mu      sync.Mutex
+	pending []int
+	genres  map[int][]*models.Genre
+}
+
+func (l *genreLoader) add(movieID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.genres[movieID]; !ok {
+		l.pending = append(l.pending, movieID)
+	}
+}
+
+func (l *genreLoader) get(movieID int) ([]*models.Genre, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) > 0 {
+		genres, err := l.db.GenresForMovies(l.pending)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range l.pending {
+			l.genres[id] = genres[id]
+		}
+		l.pending = nil
+	}
+
+	return l.genres[movieID], nil
 }
 
-func New(movies []*models.Movie) *Graph {
+func New(db repository.DatabaseRepo) (*Graph, error) {
+	g := &Graph{DB: db, persisted: newQueryStore(persistedQueryLimit)}
+
+	var genreType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Genre",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.Int,
+				},
+				"genre": &graphql.Field{
+					Type: graphql.String,
+				},
+			},
+		},
+	)
+
 	var movieType = graphql.NewObject(
 		graphql.ObjectConfig{
 			Name: "Movie",
@@ -48,17 +189,48 @@ func New(movies []*models.Movie) *Graph {
 				"image": &graphql.Field{
 					Type: graphql.String,
 				},
+				"genres": &graphql.Field{
+					Type:    graphql.NewList(genreType),
+					Resolve: g.resolveMovieGenres,
+				},
+			},
+		},
+	)
+
+	var movieInput = graphql.NewInputObject(
+		graphql.InputObjectConfig{
+			Name: "MovieInput",
+			Fields: graphql.InputObjectConfigFieldMap{
+				"title": &graphql.InputObjectFieldConfig{
+					Type: graphql.String,
+				},
+				"description": &graphql.InputObjectFieldConfig{
+					Type: graphql.String,
+				},
+				"release_date": &graphql.InputObjectFieldConfig{
+					Type:        graphql.String,
+					Description: "Release date as YYYY-MM-DD",
+				},
+				"runtime": &graphql.InputObjectFieldConfig{
+					Type: graphql.Int,
+				},
+				"mpaa_rating": &graphql.InputObjectFieldConfig{
+					Type: graphql.String,
+				},
+				"genre_ids": &graphql.InputObjectFieldConfig{
+					Type: graphql.NewList(graphql.NewNonNull(graphql.Int)),
+				},
 			},
 		},
 	)
 
-	var fields = graphql.Fields{
+	var queryFields = graphql.Fields{
 
 		"list": &graphql.Field{
 			Type:        graphql.NewList(movieType),
 			Description: "Get all movies",
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				return movies, nil
+				return g.DB.AllMovies()
 			},
 		},
 
@@ -73,11 +245,18 @@ func New(movies []*models.Movie) *Graph {
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				var theList []*models.Movie
 				search, ok := params.Args["titleContains"].(string)
-				if ok {
-					for _, currentMovie := range movies {
-						if strings.Contains(strings.ToLower(currentMovie.Title), strings.ToLower(search)) {
-							theList = append(theList, currentMovie)
-						}
+				if !ok {
+					return theList, nil
+				}
+
+				movies, err := g.DB.AllMovies()
+				if err != nil {
+					return nil, err
+				}
+
+				for _, currentMovie := range movies {
+					if strings.Contains(strings.ToLower(currentMovie.Title), strings.ToLower(search)) {
+						theList = append(theList, currentMovie)
 					}
 				}
 				return theList, nil
@@ -89,43 +268,290 @@ func New(movies []*models.Movie) *Graph {
 			Description: "Get movie by id",
 			Args: graphql.FieldConfigArgument{
 				"id": &graphql.ArgumentConfig{
-					Type: graphql.Int,
+					Type: graphql.NewNonNull(graphql.Int),
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				id, ok := p.Args["id"].(int)
-				if ok {
-					for _, movie := range movies {
-						if movie.ID == id {
-							return movie, nil
-						}
-					}
+				movie, err := g.DB.OneMovie(p.Args["id"].(int))
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil, nil
 				}
-				return nil, nil
+				return movie, err
+			},
+		},
+
+		"genres": &graphql.Field{
+			Type:        graphql.NewList(genreType),
+			Description: "Get all genres",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return g.DB.AllGenres()
+			},
+		},
+
+		"moviesByGenre": &graphql.Field{
+			Type:        graphql.NewList(movieType),
+			Description: "Get all movies in a genre",
+			Args: graphql.FieldConfigArgument{
+				"genreId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return g.DB.AllMovies(p.Args["genreId"].(int))
+			},
+		},
+	}
+
+	var mutationFields = graphql.Fields{
+
+		"insertMovie": &graphql.Field{
+			Type:        movieType,
+			Description: "Add a movie; requires the editor role",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(movieInput),
+				},
+			},
+			Resolve: g.resolveInsertMovie,
+		},
+
+		"updateMovie": &graphql.Field{
+			Type:        movieType,
+			Description: "Change the given fields of a movie; requires the editor role",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(movieInput),
+				},
 			},
+			Resolve: g.resolveUpdateMovie,
 		},
 	}
 
-	return &Graph{
-		Movies:    movies,
-		fields:    fields,
-		movieType: movieType,
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "RootQuery", Fields: queryFields}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "RootMutation", Fields: mutationFields}),
+	})
+	if err != nil {
+		return nil, err
 	}
+	g.schema = schema
+
+	return g, nil
 }
 
-func (g *Graph) Query() (*graphql.Result, error) {
-	rootQuery := graphql.ObjectConfig{Name: "RootQuery", Fields: g.fields}
-	schemaConfig := graphql.SchemaConfig{Query: graphql.NewObject(rootQuery)}
-	schema, err := graphql.NewSchema(schemaConfig)
+// Do runs a request against the schema. Errors in the request or raised while
+// resolving it are reported in the returned result, in the shape the GraphQL spec
+// prescribes. The only error Do returns itself is ErrMutationNotAllowed, when
+// allowMutations is false and the request selects a mutation.
+func (g *Graph) Do(ctx context.Context, req Request, allowMutations bool) (*graphql.Result, error) {
+	hash, err := g.resolvePersisted(&req)
 	if err != nil {
+		return errorResult(err), nil
+	}
+
+	if req.Query == "" {
+		return errorResult(errors.New("query must be provided")), nil
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, nil
+	}
+
+	validation := graphql.ValidateDocument(&g.schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, nil
+	}
+
+	if hash != "" {
+		g.persisted.put(hash, req.Query)
+	}
+
+	if op := selectOperation(doc, req.OperationName); op != nil {
+		if op.Operation == ast.OperationTypeMutation && !allowMutations {
+			return nil, ErrMutationNotAllowed
+		}
+
+		depth, complexity := measure(doc, op, g.MaxComplexity)
+		if g.MaxDepth > 0 && depth > g.MaxDepth {
+			return errorResult(fmt.Errorf("query depth %d exceeds the limit of %d", depth, g.MaxDepth)), nil
+		}
+		if g.MaxComplexity > 0 && complexity > g.MaxComplexity {
+			return errorResult(fmt.Errorf("query complexity exceeds the limit of %d", g.MaxComplexity)), nil
+		}
+	}
+
+	loader := &genreLoader{db: g.DB, genres: make(map[int][]*models.Genre)}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        g.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, loaderContextKey, loader),
+	}), nil
+}
+
+// errorResult is the result of a request that was rejected before it ran.
+func errorResult(err error) *graphql.Result {
+	formatted := gqlerrors.FormatError(err)
+
+	var extended gqlerrors.ExtendedError
+	if errors.As(err, &extended) {
+		formatted.Extensions = extended.Extensions()
+	}
+
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
+
+// resolveMovieGenres defers loading a movie's genres so that the genres of every
+// movie in a list are fetched together.
+func (g *Graph) resolveMovieGenres(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := p.Source.(*models.Movie)
+	if !ok {
+		return nil, nil
+	}
+	if movie.Genres != nil {
+		return movie.Genres, nil
+	}
+
+	loader, ok := p.Context.Value(loaderContextKey).(*genreLoader)
+	if !ok {
+		return nil, errors.New("missing genre loader in context")
+	}
+
+	loader.add(movie.ID)
+
+	return func() (interface{}, error) {
+		return loader.get(movie.ID)
+	}, nil
+}
+
+func (g *Graph) resolveInsertMovie(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireEditor(p.Context); err != nil {
 		return nil, err
 	}
 
-	params := graphql.Params{Schema: schema, RequestString: g.QueryString}
-	resp := graphql.Do(params)
-	if len(resp.Errors) > 0 {
-		return nil, errors.New("error executing query")
+	input := p.Args["input"].(map[string]interface{})
+
+	var movie models.Movie
+	err := applyMovieInput(&movie, input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case movie.Title == "":
+		return nil, errors.New("title is required")
+	case movie.ReleaseDate.IsZero():
+		return nil, errors.New("release_date is required")
+	case movie.RunTime <= 0:
+		return nil, errors.New("runtime must be greater than zero")
+	case movie.MPAARating == "":
+		return nil, errors.New("mpaa_rating is required")
+	}
+
+	movie.CreatedAt = time.Now()
+	movie.UpdatedAt = time.Now()
+
+	err = g.DB.WithTx(func(tx repository.DatabaseRepo) error {
+		movie.ID, err = tx.InsertMovie(movie)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateMovieGenres(movie.ID, movie.GenresArray)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	if g.Inserted != nil {
+		g.Inserted(movie)
+	}
+
+	return g.DB.OneMovie(movie.ID)
+}
+
+func (g *Graph) resolveUpdateMovie(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireEditor(p.Context); err != nil {
+		return nil, err
+	}
+
+	id := p.Args["id"].(int)
+	input := p.Args["input"].(map[string]interface{})
+
+	movie, err := g.DB.OneMovie(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errMovieNotFound
+		}
+		return nil, err
+	}
+
+	err = applyMovieInput(movie, input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case movie.Title == "":
+		return nil, errors.New("title must not be empty")
+	case movie.RunTime <= 0:
+		return nil, errors.New("runtime must be greater than zero")
+	}
+
+	movie.UpdatedAt = time.Now()
+
+	err = g.DB.WithTx(func(tx repository.DatabaseRepo) error {
+		err := tx.UpdateMovie(*movie)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := input["genre_ids"].([]interface{}); ok {
+			return tx.UpdateMovieGenres(id, movie.GenresArray)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return g.DB.OneMovie(id)
+}
+
+// applyMovieInput copies the fields present in a MovieInput onto movie.
+func applyMovieInput(movie *models.Movie, input map[string]interface{}) error {
+	if v, ok := input["title"].(string); ok {
+		movie.Title = strings.TrimSpace(v)
+	}
+	if v, ok := input["description"].(string); ok {
+		movie.Description = v
+	}
+	if v, ok := input["release_date"].(string); ok {
+		date, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return errors.New("release_date must be in YYYY-MM-DD format")
+		}
+		movie.ReleaseDate = date
+	}
+	if v, ok := input["runtime"].(int); ok {
+		movie.RunTime = v
+	}
+	if v, ok := input["mpaa_rating"].(string); ok {
+		movie.MPAARating = v
+	}
+	if v, ok := input["genre_ids"].([]interface{}); ok {
+		movie.GenresArray = make([]int, 0, len(v))
+		for _, id := range v {
+			movie.GenresArray = append(movie.GenresArray, id.(int))
+		}
+	}
+	return nil
 }
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// selectOperation returns the operation a request will run: the one named name, or
+// the only operation in the document when name is empty. It returns nil when there
+// is no such operation, which execution then reports.
+func selectOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var op *ast.OperationDefinition
+
+	for _, def := range doc.Definitions {
+		d, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			if op != nil {
+				return nil
+			}
+			op = d
+		} else if d.Name != nil && d.Name.Value == name {
+			return d
+		}
+	}
+
+	return op
+}
+
+// queryCost measures an operation before it runs. Depth is the deepest level of
+// nested fields, counting top level fields as 1. Complexity is the number of fields
+// that would be resolved for a single object at every level, with fragments
+// expanded where they are spread.
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	visiting  map[string]bool
+
+	maxComplexity int
+	depth         int
+	complexity    int
+}
+
+// measure walks op and returns its depth and complexity. It stops counting once
+// complexity goes over maxComplexity, so a document that spreads fragments into
+// each other many times cannot make the walk itself expensive.
+func measure(doc *ast.Document, op *ast.OperationDefinition, maxComplexity int) (depth, complexity int) {
+	c := &queryCost{
+		fragments:     make(map[string]*ast.FragmentDefinition),
+		visiting:      make(map[string]bool),
+		maxComplexity: maxComplexity,
+	}
+
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok && f.Name != nil {
+			c.fragments[f.Name.Value] = f
+		}
+	}
+
+	c.walk(op.SelectionSet, 1)
+
+	return c.depth, c.complexity
+}
+
+func (c *queryCost) exceeded() bool {
+	return c.maxComplexity > 0 && c.complexity > c.maxComplexity
+}
+
+func (c *queryCost) walk(set *ast.SelectionSet, level int) {
+	if set == nil {
+		return
+	}
+
+	for _, sel := range set.Selections {
+		if c.exceeded() {
+			return
+		}
+
+		switch s := sel.(type) {
+		case *ast.Field:
+			c.complexity++
+			if level > c.depth {
+				c.depth = level
+			}
+			c.walk(s.SelectionSet, level+1)
+
+		case *ast.InlineFragment:
+			c.walk(s.SelectionSet, level)
+
+		case *ast.FragmentSpread:
+			if s.Name == nil || c.visiting[s.Name.Value] {
+				continue
+			}
+			f, ok := c.fragments[s.Name.Value]
+			if !ok {
+				continue
+			}
+
+			c.visiting[s.Name.Value] = true
+			c.walk(f.SelectionSet, level)
+			delete(c.visiting, s.Name.Value)
+		}
+	}
+}
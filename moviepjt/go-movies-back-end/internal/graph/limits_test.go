@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func parse(t *testing.T, query string) *ast.Document {
+	t.Helper()
+
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		depth      int
+		complexity int
+	}{
+		{"flat", `{ list { id title } }`, 2, 3},
+		{"nested", `{ list { genres { genre } } get(id: 1) { title } }`, 3, 5},
+		{"inline fragment", `{ list { ... on Movie { title genres { genre } } } }`, 3, 4},
+		{"fragment spread", `{ list { ...M } get(id: 1) { ...M } } fragment M on Movie { id title }`, 2, 6},
+		// validation rejects fragment cycles, but measure still has to terminate on them
+		{"recursive fragments", `{ list { ...A } } fragment A on Movie { id ...B } fragment B on Movie { title ...A }`, 2, 3},
+		{"missing fragment", `{ list { ...Nope } }`, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parse(t, tt.query)
+
+			depth, complexity := measure(doc, selectOperation(doc, ""), 0)
+			if depth != tt.depth || complexity != tt.complexity {
+				t.Errorf("got depth %d and complexity %d, want %d and %d", depth, complexity, tt.depth, tt.complexity)
+			}
+		})
+	}
+}
+
+func TestMeasureStopsPastMaxComplexity(t *testing.T) {
+	// each fragment spreads the next one twice, so the expanded query has 2^30 fields
+	const levels = 30
+
+	var b strings.Builder
+	b.WriteString(`{ list { ...F0 } }`)
+	for i := 0; i < levels; i++ {
+		fmt.Fprintf(&b, ` fragment F%d on Movie { ...F%d ...F%d }`, i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, ` fragment F%d on Movie { title }`, levels)
+
+	doc := parse(t, b.String())
+
+	_, complexity := measure(doc, selectOperation(doc, ""), 100)
+	if complexity != 101 {
+		t.Errorf("got complexity %d, want 101", complexity)
+	}
+}
+
+func TestSelectOperation(t *testing.T) {
+	doc := parse(t, `query A { list { id } } query B { genres { id } }`)
+
+	if op := selectOperation(doc, "B"); op == nil || op.Name.Value != "B" {
+		t.Errorf("named B: got %v", op)
+	}
+	if op := selectOperation(doc, "C"); op != nil {
+		t.Errorf("unknown name: got %v", op.Name.Value)
+	}
+	if op := selectOperation(doc, ""); op != nil {
+		t.Errorf("no name with several operations: got %v", op.Name.Value)
+	}
+
+	doc = parse(t, `{ list { id } } { genres { id } }`)
+	if op := selectOperation(doc, ""); op != nil {
+		t.Error("several anonymous operations: got an operation")
+	}
+
+	doc = parse(t, `{ list { id } } fragment M on Movie { id }`)
+	if op := selectOperation(doc, ""); op == nil {
+		t.Error("no name with a single operation: got nil")
+	}
+}
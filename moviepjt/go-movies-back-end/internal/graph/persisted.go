@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// persistedQueryLimit is how many persisted query documents are kept in memory.
+// When it is reached the oldest one is dropped; a client whose hash is no longer
+// known just sends the full document again.
+const persistedQueryLimit = 1000
+
+// persistedQuery is the persistedQuery request extension clients send to refer to
+// a document by the hex encoded SHA-256 hash of its text.
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// codedError is a GraphQL error with an extensions.code clients can match on.
+type codedError struct {
+	message string
+	code    string
+}
+
+func (e *codedError) Error() string { return e.message }
+
+func (e *codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+var (
+	errPersistedQueryNotFound = &codedError{"PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND"}
+	errPersistedQueryVersion  = &codedError{"unsupported persisted query version", "PERSISTED_QUERY_NOT_SUPPORTED"}
+	errPersistedQueryHash     = &codedError{"provided sha256Hash does not match query", "PERSISTED_QUERY_HASH_MISMATCH"}
+	errInvalidExtensions      = &codedError{"invalid persistedQuery extension", "BAD_REQUEST"}
+)
+
+// queryStore maps persisted query hashes to their documents.
+type queryStore struct {
+	mu      sync.Mutex
+	limit   int
+	queries map[string]string
+	order   []string
+}
+
+func newQueryStore(limit int) *queryStore {
+	return &queryStore{limit: limit, queries: make(map[string]string)}
+}
+
+func (s *queryStore) get(hash string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query, ok := s.queries[hash]
+	return query, ok
+}
+
+func (s *queryStore) put(hash, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.queries[hash]; ok {
+		return
+	}
+
+	if len(s.order) >= s.limit {
+		delete(s.queries, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.queries[hash] = query
+	s.order = append(s.order, hash)
+}
+
+// resolvePersisted fills in the document of a request that refers to a persisted
+// query. It returns the hash to remember the document under once it has been
+// validated, or an empty string if there is nothing to remember.
+func (g *Graph) resolvePersisted(req *Request) (string, error) {
+	raw, ok := req.Extensions["persistedQuery"]
+	if !ok {
+		return "", nil
+	}
+
+	var pq persistedQuery
+	err := json.Unmarshal(raw, &pq)
+	if err != nil {
+		return "", errInvalidExtensions
+	}
+
+	if pq.Version != 1 {
+		return "", errPersistedQueryVersion
+	}
+
+	hash := strings.ToLower(pq.Sha256Hash)
+
+	if req.Query == "" {
+		query, ok := g.persisted.get(hash)
+		if !ok {
+			return "", errPersistedQueryNotFound
+		}
+		req.Query = query
+		return "", nil
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return "", errPersistedQueryHash
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+// MPAARatings lists the ratings a movie can have, from the mildest up. Sorting
+// by rating follows this order rather than the alphabet.
+var MPAARatings = []string{"G", "PG", "PG13", "R", "NC17", "18A"}
+
+// MovieSortSafelist holds the values MovieFilter.Sort may take. A leading "-"
+// sorts in descending order.
+var MovieSortSafelist = []string{
+	"title", "release_date", "runtime", "mpaa_rating",
+	"-title", "-release_date", "-runtime", "-mpaa_rating",
+}
+
+// MovieFilter narrows, orders and pages a movie listing. Empty fields don't
+// filter, and a PageSize of 0 returns every matching movie.
+type MovieFilter struct {
+	Page     int
+	PageSize int
+	Sort     string
+
+	// Ratings and GenreIDs match movies with any of the given values.
+	Ratings  []string
+	GenreIDs []int
+
+	// YearFrom and YearTo bound the release year, both inclusive.
+	YearFrom int
+	YearTo   int
+}
+
+// SortField returns the field to sort by without its direction, or "title" when
+// Sort is empty. It panics if Sort is not in MovieSortSafelist, since the field
+// ends up in SQL.
+func (f MovieFilter) SortField() string {
+	if f.Sort == "" {
+		return "title"
+	}
+
+	for _, safeValue := range MovieSortSafelist {
+		if f.Sort == safeValue {
+			return strings.TrimPrefix(f.Sort, "-")
+		}
+	}
+	panic("unsafe sort parameter: " + f.Sort)
+}
+
+// Descending reports whether the listing is sorted in descending order.
+func (f MovieFilter) Descending() bool {
+	return strings.HasPrefix(f.Sort, "-")
+}
+
+// Offset returns how many movies come before the requested page.
+func (f MovieFilter) Offset() int {
+	if f.PageSize == 0 || f.Page < 1 {
+		return 0
+	}
+	return (f.Page - 1) * f.PageSize
+}
+
+// Metadata describes the page of a listing. It is empty when nothing matched.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+// NewMetadata works out the pages of a listing of totalRecords movies. An
+// unpaged listing counts as a single page.
+func NewMetadata(totalRecords int, f MovieFilter) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	if f.PageSize == 0 {
+		return Metadata{
+			CurrentPage:  1,
+			PageSize:     totalRecords,
+			FirstPage:    1,
+			LastPage:     1,
+			TotalRecords: totalRecords,
+		}
+	}
+
+	return Metadata{
+		CurrentPage:  f.Page,
+		PageSize:     f.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(f.PageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token. ID is the
+// token's jti claim; every token issued by rotating another one shares its FamilyID.
+type RefreshToken struct {
+	ID        string     `json:"id"`
+	FamilyID  string     `json:"family_id"`
+	UserID    int        `json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"-"`
+}
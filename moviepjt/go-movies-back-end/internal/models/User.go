@@ -11,12 +11,26 @@ type User struct {
 	ID        int       `json:"id"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
-	Email     string    `jsom:"email"`
-	Password  string    `json:"password"`
+	Email     string    `json:"email"`
+	Password  string    `json:"-"`
 	CreatedAt time.Time `json:"-"`
 	UpdatedAt time.Time `json:"-"`
 }
 
+// bcryptCost is the work factor for new password hashes.
+const bcryptCost = 12
+
+// SetPassword replaces the user's password with the bcrypt hash of plainText.
+func (u *User) SetPassword(plainText string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plainText), bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	u.Password = string(hash)
+	return nil
+}
+
 func (u *User) PasswordMatches(plainText string) (bool, error) {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(plainText))
 	if err != nil {
@@ -0,0 +1,95 @@
+package posters
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// extensions maps the image types posters are stored as to file extensions.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// DiskCache keeps one poster per movie in a directory, named after the movie id.
+type DiskCache struct {
+	Dir string
+}
+
+// NewDiskCache creates dir if it doesn't exist yet.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{Dir: dir}, nil
+}
+
+// Save stores the poster of a movie, replacing any poster it had before. The file
+// is written under a temporary name first, so readers never see half a poster.
+func (c *DiskCache) Save(movieID int, p *Poster) error {
+	ext, ok := extensions[p.ContentType]
+	if !ok {
+		return errors.New("unsupported poster type " + p.ContentType)
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, ".poster-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(p.Data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.Delete(movieID)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(movieID, ext))
+}
+
+// Path returns the file holding the poster of a movie, or ErrNotFound.
+func (c *DiskCache) Path(movieID int) (string, error) {
+	for _, ext := range extensions {
+		path := c.path(movieID, ext)
+
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// Delete removes the poster of a movie, if there is one.
+func (c *DiskCache) Delete(movieID int) error {
+	for _, ext := range extensions {
+		err := os.Remove(c.path(movieID, ext))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *DiskCache) path(movieID int, ext string) string {
+	return filepath.Join(c.Dir, strconv.Itoa(movieID)+ext)
+}
@@ -0,0 +1,25 @@
+// Package posters finds poster images for movies and keeps copies of them on disk,
+// so the API can serve posters itself instead of sending clients to a third party.
+package posters
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by a Provider that has no poster for a title.
+var ErrNotFound = errors.New("poster not found")
+
+// maxPosterBytes caps the size of a downloaded poster.
+const maxPosterBytes = 5 << 20
+
+// Poster is an image and its media type.
+type Poster struct {
+	Data        []byte
+	ContentType string
+}
+
+// Provider looks up the poster for a movie title.
+type Provider interface {
+	Poster(ctx context.Context, title string) (*Poster, error)
+}
@@ -0,0 +1,127 @@
+package posters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// jpeg is enough of a JPEG header for content sniffing.
+var jpeg = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+
+func newTMDBServer(t *testing.T, handler http.HandlerFunc) *TMDB {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	return NewTMDB("secret-key", ts.URL+"/3/", ts.URL+"/img", 200*time.Millisecond)
+}
+
+func TestTMDB(t *testing.T) {
+	p := newTMDBServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/3/search/movie":
+			if r.URL.Query().Get("api_key") != "secret-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Query().Get("query") {
+			case "Alien":
+				w.Write([]byte(`{"results":[{"poster_path":""},{"poster_path":"/alien.jpg"}]}`))
+			case "Slow":
+				time.Sleep(time.Second)
+			default:
+				w.Write([]byte(`{"results":[]}`))
+			}
+		case "/img/alien.jpg":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(jpeg)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	poster, err := p.Poster(context.Background(), "Alien")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poster.ContentType != "image/jpeg" || string(poster.Data) != string(jpeg) {
+		t.Errorf("got %s poster %q", poster.ContentType, poster.Data)
+	}
+
+	_, err = p.Poster(context.Background(), "Unknown")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("no results: got %v, want ErrNotFound", err)
+	}
+
+	start := time.Now()
+	_, err = p.Poster(context.Background(), "Slow")
+	if err == nil || time.Since(start) > 900*time.Millisecond {
+		t.Errorf("slow provider: got %v after %s, want a timeout", err, time.Since(start))
+	}
+
+	p.APIKey = "wrong"
+	_, err = p.Poster(context.Background(), "Alien")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("rejected api key: got %v", err)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Path(1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("empty cache: got %v, want ErrNotFound", err)
+	}
+
+	png, err := Stub{}.Poster(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []*Poster{{Data: jpeg, ContentType: "image/jpeg"}, png} {
+		err = c.Save(1, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path, err := c.Path(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(p.Data) {
+			t.Errorf("%s: read back different data", p.ContentType)
+		}
+	}
+
+	entries, _ := os.ReadDir(c.Dir)
+	if len(entries) != 1 {
+		t.Errorf("replacing a poster left %d files behind", len(entries))
+	}
+
+	err = c.Save(2, &Poster{Data: []byte("<svg/>"), ContentType: "image/svg+xml"})
+	if err == nil {
+		t.Error("saved an unsupported image type")
+	}
+
+	err = c.Delete(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Path(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("after delete: got %v, want ErrNotFound", err)
+	}
+}
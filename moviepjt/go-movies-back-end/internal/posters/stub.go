@@ -0,0 +1,33 @@
+package posters
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+)
+
+// Stub is an offline Provider for tests and development. It returns the same
+// plain placeholder image for every title, or Err when it is set.
+type Stub struct {
+	Err error
+}
+
+func (s Stub) Poster(ctx context.Context, title string) (*Poster, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	img := image.NewGray(image.Rect(0, 0, 2, 3))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Poster{Data: buf.Bytes(), ContentType: "image/png"}, nil
+}
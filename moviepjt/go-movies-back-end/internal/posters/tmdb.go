@@ -0,0 +1,127 @@
+package posters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TMDB finds posters with The Movie Database search API and downloads them from
+// its image server.
+type TMDB struct {
+	APIKey string
+	// BaseURL is the API root, e.g. https://api.themoviedb.org/3.
+	BaseURL string
+	// ImageBaseURL is prepended to poster paths, e.g. https://image.tmdb.org/t/p/w200.
+	ImageBaseURL string
+	Client       *http.Client
+}
+
+// NewTMDB returns a TMDB provider whose requests give up after timeout.
+func NewTMDB(apiKey, baseURL, imageBaseURL string, timeout time.Duration) *TMDB {
+	return &TMDB{
+		APIKey:       apiKey,
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		ImageBaseURL: strings.TrimSuffix(imageBaseURL, "/"),
+		Client:       &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *TMDB) Poster(ctx context.Context, title string) (*Poster, error) {
+	path, err := p.search(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.get(ctx, p.ImageBaseURL+path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPosterBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPosterBytes {
+		return nil, fmt.Errorf("tmdb: poster for %q is larger than %d bytes", title, maxPosterBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("tmdb: poster for %q is %s, not an image", title, contentType)
+	}
+
+	return &Poster{Data: data, ContentType: contentType}, nil
+}
+
+// search returns the poster path of the best match for title.
+func (p *TMDB) search(ctx context.Context, title string) (string, error) {
+	q := url.Values{}
+	q.Set("api_key", p.APIKey)
+	q.Set("query", title)
+
+	resp, err := p.get(ctx, p.BaseURL+"/search/movie?"+q.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Results []struct {
+			PosterPath string `json:"poster_path"`
+		} `json:"results"`
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("tmdb: decoding search results: %w", err)
+	}
+
+	for _, r := range result.Results {
+		if r.PosterPath != "" {
+			return r.PosterPath, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// get sends a GET request and returns the response if it succeeded.
+func (p *TMDB) get(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, image/*")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		// the error includes the URL, which carries the api key
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return nil, fmt.Errorf("tmdb: %s: %w", req.URL.Path, uerr.Err)
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("tmdb: %s: unexpected status %s", req.URL.Path, resp.Status)
+	}
+
+	return resp, nil
+}
@@ -2,14 +2,26 @@ package dbrepo
 
 import (
 	"backend/internal/models"
+	"backend/internal/repository"
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
 type PostgresDBRepo struct {
 	DB *sql.DB
+
+	// tx is set on the repository WithTx hands to its callback
+	tx *sql.Tx
+}
+
+// queryer is what *sql.DB and *sql.Tx have in common.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 const dbTimeout = time.Second * 3
@@ -18,36 +30,126 @@ func (m *PostgresDBRepo) Connection() *sql.DB {
 	return m.DB
 }
 
+func (m *PostgresDBRepo) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+// conn returns the transaction the repository is bound to, or the pool.
+func (m *PostgresDBRepo) conn() queryer {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.DB
+}
+
+// WithTx runs fn with a repository whose methods all run in one transaction. The
+// transaction is committed if fn returns nil and rolled back otherwise, including
+// when fn panics. Calling WithTx on a repository that is already in a transaction
+// runs fn in that transaction.
+func (m *PostgresDBRepo) WithTx(fn func(repo repository.DatabaseRepo) error) (err error) {
+	if m.tx != nil {
+		return fn(m)
+	}
+
+	tx, err := m.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = fn(&PostgresDBRepo{DB: m.DB, tx: tx})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (m *PostgresDBRepo) AllMovies(genre ...int) ([]*models.Movie, error) {
+	movies, _, err := m.ListMovies(models.MovieFilter{GenreIDs: genre})
+	return movies, err
+}
+
+// movieSortColumns maps the sort fields of a MovieFilter to SQL. Ratings sort in
+// the order of models.MPAARatings, with unknown ratings last.
+var movieSortColumns = map[string]string{
+	"title":        "title",
+	"release_date": "release_date",
+	"runtime":      "runtime",
+	"mpaa_rating":  "array_position(array['" + strings.Join(models.MPAARatings, "','") + "'], mpaa_rating::text)",
+}
+
+// movieFilterWhere selects the movies a MovieFilter matches; $1 to $4 are the
+// ratings, the year range and the genre ids.
+const movieFilterWhere = `
+			(cardinality($1::text[]) = 0 or mpaa_rating = any($1))
+			and ($2 = 0 or release_date >= make_date($2, 1, 1))
+			and ($3 = 0 or release_date < make_date($3 + 1, 1, 1))
+			and (cardinality($4::int[]) = 0 or id in (select movie_id from movies_genres where genre_id = any($4)))`
+
+// ListMovies returns the page of movies the filter selects, along with the
+// metadata of the whole listing.
+func (m *PostgresDBRepo) ListMovies(filter models.MovieFilter) ([]*models.Movie, models.Metadata, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	where := ""
-	if len(genre) > 0 {
-		where = fmt.Sprintf("where id in (select movie_id from movies_genres where genre_id = %d)", genre[0])
+	direction := "asc"
+	if filter.Descending() {
+		direction = "desc"
 	}
 
+	// the window function counts every matching row, not just this page
 	query := fmt.Sprintf(`
-		SELECT 
-			id, title, release_date, runtime,
+		SELECT
+			count(*) over(), id, title, release_date, runtime,
 			mpaa_rating, description, coalesce(image, ''),
 			created_at, updated_at
-		FROM 
-			movies %s
+		FROM
+			movies
+		WHERE %s
 		ORDER BY
-			title
-		`, where)
-	rows, err := m.DB.QueryContext(ctx, query)
+			%s %s, id
+		LIMIT $5 OFFSET $6
+		`, movieFilterWhere, movieSortColumns[filter.SortField()], direction)
+
+	ratings := filter.Ratings
+	if ratings == nil {
+		ratings = []string{}
+	}
+	genreIDs := filter.GenreIDs
+	if genreIDs == nil {
+		genreIDs = []int{}
+	}
+
+	// a null limit returns every row
+	var limit any
+	if filter.PageSize > 0 {
+		limit = filter.PageSize
+	}
+
+	rows, err := m.conn().QueryContext(ctx, query,
+		ratings, filter.YearFrom, filter.YearTo, genreIDs, limit, filter.Offset())
 	if err != nil {
-		return nil, err
+		return nil, models.Metadata{}, err
 	}
 	defer rows.Close()
 
+	totalRecords := 0
 	var movies []*models.Movie
 
 	for rows.Next() {
 		var movie models.Movie
 		err := rows.Scan(
+			&totalRecords,
 			&movie.ID,
 			&movie.Title,
 			&movie.ReleaseDate,
@@ -59,13 +161,28 @@ func (m *PostgresDBRepo) AllMovies(genre ...int) ([]*models.Movie, error) {
 			&movie.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, models.Metadata{}, err
 		}
 
 		movies = append(movies, &movie)
 	}
 
-	return movies, nil
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	// a page past the end has no rows to carry the window count, so count separately
+	if len(movies) == 0 && filter.Offset() > 0 {
+		query = `SELECT count(*) FROM movies WHERE ` + movieFilterWhere
+
+		err = m.conn().QueryRowContext(ctx, query,
+			ratings, filter.YearFrom, filter.YearTo, genreIDs).Scan(&totalRecords)
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+	}
+
+	return movies, models.NewMetadata(totalRecords, filter), nil
 }
 
 func (m *PostgresDBRepo) GetUserByEmail(email string) (*models.User, error) {
@@ -81,7 +198,7 @@ func (m *PostgresDBRepo) GetUserByEmail(email string) (*models.User, error) {
 		`
 
 	var user models.User
-	row := m.DB.QueryRowContext(ctx, query, email)
+	row := m.conn().QueryRowContext(ctx, query, email)
 
 	err := row.Scan(
 		&user.ID,
@@ -113,7 +230,7 @@ func (m *PostgresDBRepo) GetUserByID(id int) (*models.User, error) {
 		`
 
 	var user models.User
-	row := m.DB.QueryRowContext(ctx, query, id)
+	row := m.conn().QueryRowContext(ctx, query, id)
 
 	err := row.Scan(
 		&user.ID,
@@ -132,6 +249,129 @@ func (m *PostgresDBRepo) GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
+func (m *PostgresDBRepo) InsertUser(user models.User) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `insert into users (first_name, last_name, email, password, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id`
+
+	var newID int
+
+	err := m.conn().QueryRowContext(ctx, stmt,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Password,
+		user.CreatedAt,
+		user.UpdatedAt,
+	).Scan(&newID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+func (m *PostgresDBRepo) UpdateUser(user models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `update users set first_name = $1, last_name = $2, email = $3,
+				password = $4, updated_at = $5 where id = $6`
+
+	_, err := m.conn().ExecContext(ctx, stmt,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Password,
+		user.UpdatedAt,
+		user.ID,
+	)
+
+	return err
+}
+
+func (m *PostgresDBRepo) ListUsers() ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id, email, first_name, last_name, password,
+			created_at, updated_at
+		FROM users
+		ORDER BY id
+		`
+
+	rows, err := m.conn().QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.FirstName,
+			&user.LastName,
+			&user.Password,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (m *PostgresDBRepo) GetUserRoles(userID int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			r.name
+		FROM user_roles ur
+			LEFT JOIN roles r ON (r.id = ur.role_id)
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+		`
+
+	rows, err := m.conn().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+
+	for rows.Next() {
+		var role string
+		err := rows.Scan(&role)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
 func (m *PostgresDBRepo) OneMovie(id int) (*models.Movie, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
@@ -139,7 +379,7 @@ func (m *PostgresDBRepo) OneMovie(id int) (*models.Movie, error) {
 	query := `select id, title, release_date, runtime, mpaa_rating, 
 				description, coalesce(image, ''), created_at, updated_at
 				from movies where id = $1`
-	row := m.DB.QueryRowContext(ctx, query, id)
+	row := m.conn().QueryRowContext(ctx, query, id)
 
 	var movie models.Movie
 
@@ -164,7 +404,7 @@ func (m *PostgresDBRepo) OneMovie(id int) (*models.Movie, error) {
 		where mg.movie_id = $1
 		order by g.genre`
 
-	rows, err := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.conn().QueryContext(ctx, query, id)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
@@ -197,7 +437,7 @@ func (m *PostgresDBRepo) OneMovieForEdit(id int) (*models.Movie, []*models.Genre
 		description, coalesce(image, ''), created_at, updated_at
 		from movies where id = $1`
 
-	row := m.DB.QueryRowContext(ctx, query, id)
+	row := m.conn().QueryRowContext(ctx, query, id)
 
 	var movie models.Movie
 
@@ -223,7 +463,7 @@ func (m *PostgresDBRepo) OneMovieForEdit(id int) (*models.Movie, []*models.Genre
 		where mg.movie_id = $1
 		order by g.genre`
 
-	rows, err := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.conn().QueryContext(ctx, query, id)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, nil, err
 	}
@@ -252,7 +492,7 @@ func (m *PostgresDBRepo) OneMovieForEdit(id int) (*models.Movie, []*models.Genre
 	var allGenres []*models.Genre
 
 	query = "select id, genre from genres order by genre"
-	gRows, err := m.DB.QueryContext(ctx, query)
+	gRows, err := m.conn().QueryContext(ctx, query)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -280,7 +520,7 @@ func (m *PostgresDBRepo) AllGenres() ([]*models.Genre, error) {
 
 	query := `select id, genre, created_at, updated_at from genres order by genre`
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.conn().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -307,6 +547,161 @@ func (m *PostgresDBRepo) AllGenres() ([]*models.Genre, error) {
 	return genres, nil
 }
 
+// OneGenre locks the row until the transaction ends when it runs inside
+// WithTx, so no movie can be given the genre while it is being deleted.
+func (m *PostgresDBRepo) OneGenre(id int) (*models.Genre, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := `select id, genre, created_at, updated_at from genres where id = $1`
+	if m.tx != nil {
+		query += ` for update`
+	}
+
+	var g models.Genre
+	err := m.conn().QueryRowContext(ctx, query, id).Scan(
+		&g.ID,
+		&g.Genre,
+		&g.CreatedAt,
+		&g.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+func (m *PostgresDBRepo) InsertGenre(genre models.Genre) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `insert into genres (genre, created_at, updated_at) values ($1, $2, $3)
+		returning id`
+
+	var newID int
+	err := m.conn().QueryRowContext(ctx, stmt,
+		genre.Genre,
+		genre.CreatedAt,
+		genre.UpdatedAt,
+	).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+func (m *PostgresDBRepo) UpdateGenre(genre models.Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `update genres set genre = $1, updated_at = $2 where id = $3`
+
+	_, err := m.conn().ExecContext(ctx, stmt,
+		genre.Genre,
+		genre.UpdatedAt,
+		genre.ID,
+	)
+
+	return err
+}
+
+// GenreMovieCount returns how many movies have the genre.
+func (m *PostgresDBRepo) GenreMovieCount(id int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := `select count(distinct movie_id) from movies_genres where genre_id = $1`
+
+	var count int
+	err := m.conn().QueryRowContext(ctx, query, id).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ReassignGenre moves every movie of genre from to genre to. Movies that already
+// have both genres keep a single link to the new one.
+func (m *PostgresDBRepo) ReassignGenre(from, to int) error {
+	return m.WithTx(func(repo repository.DatabaseRepo) error {
+		tx := repo.(*PostgresDBRepo)
+
+		ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+		defer cancel()
+
+		stmt := `insert into movies_genres (movie_id, genre_id)
+			select distinct movie_id, $2::int from movies_genres
+			where genre_id = $1
+			and movie_id not in (select movie_id from movies_genres where genre_id = $2)`
+
+		_, err := tx.conn().ExecContext(ctx, stmt, from, to)
+		if err != nil {
+			return err
+		}
+
+		stmt = `delete from movies_genres where genre_id = $1`
+
+		_, err = tx.conn().ExecContext(ctx, stmt, from)
+
+		return err
+	})
+}
+
+// DeleteGenre deletes a genre. The schema cascades the delete to movies_genres,
+// so callers check GenreMovieCount first.
+func (m *PostgresDBRepo) DeleteGenre(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `delete from genres where id = $1`
+
+	_, err := m.conn().ExecContext(ctx, stmt, id)
+
+	return err
+}
+
+// GenresForMovies loads the genres of several movies in a single query, keyed by movie id.
+func (m *PostgresDBRepo) GenresForMovies(movieIDs []int) (map[int][]*models.Genre, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	genres := make(map[int][]*models.Genre, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return genres, nil
+	}
+
+	query := `select mg.movie_id, g.id, g.genre from movies_genres mg
+		join genres g on (mg.genre_id = g.id)
+		where mg.movie_id = any($1)
+		order by g.genre`
+
+	rows, err := m.conn().QueryContext(ctx, query, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int
+		var g models.Genre
+		err := rows.Scan(
+			&movieID,
+			&g.ID,
+			&g.Genre,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		genres[movieID] = append(genres[movieID], &g)
+	}
+
+	return genres, rows.Err()
+}
+
 func (m *PostgresDBRepo) InsertMovie(movie models.Movie) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
@@ -317,7 +712,7 @@ func (m *PostgresDBRepo) InsertMovie(movie models.Movie) (int, error) {
 
 	var newID int
 
-	err := m.DB.QueryRowContext(ctx, stmt,
+	err := m.conn().QueryRowContext(ctx, stmt,
 		movie.Title,
 		movie.Description,
 		movie.ReleaseDate,
@@ -343,7 +738,7 @@ func (m *PostgresDBRepo) UpdateMovie(movie models.Movie) error {
 				runtime = $4, mpaa_rating = $5,
 				updated_at = $6, image = $7 where id = $8`
 
-	_, err := m.DB.ExecContext(ctx, stmt,
+	_, err := m.conn().ExecContext(ctx, stmt,
 		movie.Title,
 		movie.Description,
 		movie.ReleaseDate,
@@ -361,26 +756,47 @@ func (m *PostgresDBRepo) UpdateMovie(movie models.Movie) error {
 	return nil
 }
 
-func (m *PostgresDBRepo) UpdateMovieGenres(id int, genreIds []int) error {
+// UpdateMovieImage sets only the image of a movie, so a poster fetched in the
+// background can't overwrite changes made to the movie in the meantime.
+func (m *PostgresDBRepo) UpdateMovieImage(id int, image string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	stmt := `delete from movies_genres where movie_id = $1`
+	stmt := `update movies set image = $1 where id = $2`
 
-	_, err := m.DB.ExecContext(ctx, stmt, id)
-	if err != nil {
-		return err
-	}
+	_, err := m.conn().ExecContext(ctx, stmt, image, id)
+
+	return err
+}
+
+// UpdateMovieGenres replaces the genres of a movie with genreIds, with a single
+// insert for all of them. Both steps run in one transaction, joining the caller's
+// if there is one.
+func (m *PostgresDBRepo) UpdateMovieGenres(id int, genreIds []int) error {
+	return m.WithTx(func(repo repository.DatabaseRepo) error {
+		tx := repo.(*PostgresDBRepo)
+
+		ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+		defer cancel()
 
-	for _, n := range genreIds {
-		stmt := `insert into movies_genres (movie_id, genre_id) values ($1, $2)`
-		_, err := m.DB.ExecContext(ctx, stmt, id, n)
+		stmt := `delete from movies_genres where movie_id = $1`
+
+		_, err := tx.conn().ExecContext(ctx, stmt, id)
 		if err != nil {
 			return err
 		}
-	}
 
-	return nil
+		if len(genreIds) == 0 {
+			return nil
+		}
+
+		stmt = `insert into movies_genres (movie_id, genre_id)
+			select $1, genre_id from unnest($2::int[]) as genre_id`
+
+		_, err = tx.conn().ExecContext(ctx, stmt, id, genreIds)
+
+		return err
+	})
 }
 
 func (m *PostgresDBRepo) DeleteMovie(id int) error {
@@ -389,10 +805,105 @@ func (m *PostgresDBRepo) DeleteMovie(id int) error {
 
 	stmt := `delete from movies where id = $1`
 
-	_, err := m.DB.ExecContext(ctx, stmt, id)
+	_, err := m.conn().ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (m *PostgresDBRepo) InsertRefreshToken(token models.RefreshToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `insert into refresh_tokens (id, family_id, user_id, expires_at, created_at)
+		values ($1, $2, $3, $4, $5)`
+
+	_, err := m.conn().ExecContext(ctx, stmt,
+		token.ID,
+		token.FamilyID,
+		token.UserID,
+		token.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+func (m *PostgresDBRepo) GetRefreshToken(id string) (*models.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id, family_id, user_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE id = $1
+		`
+
+	var token models.RefreshToken
+	row := m.conn().QueryRowContext(ctx, query, id)
+
+	err := row.Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.UserID,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marks a token as used and reports whether it was still
+// unused and unrevoked, so two concurrent refreshes can't both succeed.
+func (m *PostgresDBRepo) MarkRefreshTokenUsed(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `update refresh_tokens set used_at = $1
+		where id = $2 and used_at is null and revoked_at is null`
+
+	result, err := m.conn().ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (m *PostgresDBRepo) RevokeRefreshTokenFamily(familyID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `update refresh_tokens set revoked_at = $1
+		where family_id = $2 and revoked_at is null`
+
+	_, err := m.conn().ExecContext(ctx, stmt, time.Now(), familyID)
+
+	return err
+}
+
+func (m *PostgresDBRepo) RevokeUserRefreshTokens(userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt := `update refresh_tokens set revoked_at = $1
+		where user_id = $2 and revoked_at is null`
+
+	_, err := m.conn().ExecContext(ctx, stmt, time.Now(), userID)
+
+	return err
+}
@@ -2,20 +2,48 @@ package repository
 
 import (
 	"backend/internal/models"
+	"context"
 	"database/sql"
 )
 
 type DatabaseRepo interface {
 	Connection() *sql.DB
+
+	// Ping checks that the database can be reached.
+	Ping(ctx context.Context) error
+
+	// WithTx runs fn with a repository whose methods all take part in a single
+	// transaction, committed when fn returns nil and rolled back otherwise.
+	WithTx(fn func(repo DatabaseRepo) error) error
+
 	AllMovies(genre ...int) ([]*models.Movie, error)
+	ListMovies(filter models.MovieFilter) ([]*models.Movie, models.Metadata, error)
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByID(id int) (*models.User, error)
+	GetUserRoles(userID int) ([]string, error)
+	InsertUser(user models.User) (int, error)
+	UpdateUser(user models.User) error
+	ListUsers() ([]*models.User, error)
+
+	InsertRefreshToken(token models.RefreshToken) error
+	GetRefreshToken(id string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(id string) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID int) error
 
 	OneMovie(id int) (*models.Movie, error)
 	OneMovieForEdit(id int) (*models.Movie, []*models.Genre, error)
 	AllGenres() ([]*models.Genre, error)
+	OneGenre(id int) (*models.Genre, error)
+	InsertGenre(genre models.Genre) (int, error)
+	UpdateGenre(genre models.Genre) error
+	GenreMovieCount(id int) (int, error)
+	ReassignGenre(from, to int) error
+	DeleteGenre(id int) error
+	GenresForMovies(movieIDs []int) (map[int][]*models.Genre, error)
 	InsertMovie(movie models.Movie) (int, error)
 	UpdateMovieGenres(id int, genreIds []int) error
 	UpdateMovie(movie models.Movie) error
+	UpdateMovieImage(id int, image string) error
 	DeleteMovie(id int) error
 }
@@ -0,0 +1,793 @@
+// Package testrepo is an in-memory repository.DatabaseRepo for tests. It follows
+// the behaviour of the Postgres repository, including sql.ErrNoRows for missing
+// rows, the unique and foreign key constraints of the schema and the order rows
+// are returned in, so handlers can be tested without a database.
+package testrepo
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// Postgres error codes returned for constraint violations, as pgconn.PgError codes.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+var _ repository.DatabaseRepo = (*TestDBRepo)(nil)
+
+type TestDBRepo struct {
+	mu sync.Mutex
+	// txMu lets one transaction run at a time
+	txMu sync.Mutex
+
+	movies        map[int]models.Movie
+	genres        map[int]models.Genre
+	movieGenres   map[int][]int
+	users         map[int]models.User
+	roles         map[int][]string
+	refreshTokens map[string]models.RefreshToken
+
+	nextMovieID int
+	nextGenreID int
+	nextUserID  int
+
+	pingErr error
+
+	// genresForMoviesCalls counts GenresForMovies calls, so tests can check
+	// that genres are loaded in batches
+	genresForMoviesCalls int
+}
+
+// New returns an empty repository. Use AddGenre and SetUserRoles to create the
+// rows DatabaseRepo has no methods for.
+func New() *TestDBRepo {
+	return &TestDBRepo{
+		movies:        make(map[int]models.Movie),
+		genres:        make(map[int]models.Genre),
+		movieGenres:   make(map[int][]int),
+		users:         make(map[int]models.User),
+		roles:         make(map[int][]string),
+		refreshTokens: make(map[string]models.RefreshToken),
+		nextMovieID:   1,
+		nextGenreID:   1,
+		nextUserID:    1,
+	}
+}
+
+// Connection returns nil; there is no database behind this repository.
+func (m *TestDBRepo) Connection() *sql.DB {
+	return nil
+}
+
+// Ping returns the error set with SetPingError, if any.
+func (m *TestDBRepo) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.pingErr
+}
+
+// SetPingError makes Ping fail with err, as if the database were down, or
+// succeed again when err is nil.
+func (m *TestDBRepo) SetPingError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pingErr = err
+}
+
+// WithTx runs fn with a repository that shares this one's data. If fn returns an
+// error or panics, every change made since WithTx was called is undone. Changes
+// made outside the transaction while it runs are undone too, so tests should not
+// write to the repository concurrently with a transaction.
+func (m *TestDBRepo) WithTx(fn func(repo repository.DatabaseRepo) error) (err error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	saved := m.snapshot()
+
+	defer func() {
+		if p := recover(); p != nil {
+			m.restore(saved)
+			panic(p)
+		}
+		if err != nil {
+			m.restore(saved)
+		}
+	}()
+
+	return fn(txRepo{m})
+}
+
+// txRepo is the repository WithTx hands to its callback; nested calls to WithTx
+// join the transaction that is already running.
+type txRepo struct {
+	*TestDBRepo
+}
+
+func (r txRepo) WithTx(fn func(repo repository.DatabaseRepo) error) error {
+	return fn(r)
+}
+
+func (m *TestDBRepo) snapshot() *TestDBRepo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := &TestDBRepo{
+		movies:        make(map[int]models.Movie, len(m.movies)),
+		genres:        make(map[int]models.Genre, len(m.genres)),
+		movieGenres:   make(map[int][]int, len(m.movieGenres)),
+		users:         make(map[int]models.User, len(m.users)),
+		roles:         make(map[int][]string, len(m.roles)),
+		refreshTokens: make(map[string]models.RefreshToken, len(m.refreshTokens)),
+		nextMovieID:   m.nextMovieID,
+		nextGenreID:   m.nextGenreID,
+		nextUserID:    m.nextUserID,
+	}
+
+	for k, v := range m.movies {
+		c.movies[k] = v
+	}
+	for k, v := range m.genres {
+		c.genres[k] = v
+	}
+	for k, v := range m.movieGenres {
+		c.movieGenres[k] = append([]int(nil), v...)
+	}
+	for k, v := range m.users {
+		c.users[k] = v
+	}
+	for k, v := range m.roles {
+		c.roles[k] = append([]string(nil), v...)
+	}
+	for k, v := range m.refreshTokens {
+		c.refreshTokens[k] = v
+	}
+
+	return c
+}
+
+func (m *TestDBRepo) restore(saved *TestDBRepo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.movies = saved.movies
+	m.genres = saved.genres
+	m.movieGenres = saved.movieGenres
+	m.users = saved.users
+	m.roles = saved.roles
+	m.refreshTokens = saved.refreshTokens
+	m.nextMovieID = saved.nextMovieID
+	m.nextGenreID = saved.nextGenreID
+	m.nextUserID = saved.nextUserID
+}
+
+// AddGenre creates a genre and returns its id.
+func (m *TestDBRepo) AddGenre(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextGenreID
+	m.nextGenreID++
+
+	now := time.Now()
+	m.genres[id] = models.Genre{ID: id, Genre: name, CreatedAt: now, UpdatedAt: now}
+
+	return id
+}
+
+// SetUserRoles replaces the roles granted to a user.
+func (m *TestDBRepo) SetUserRoles(userID int, roles ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.roles[userID] = append([]string(nil), roles...)
+}
+
+// GenresForMoviesCalls returns how many times GenresForMovies has been called.
+func (m *TestDBRepo) GenresForMoviesCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.genresForMoviesCalls
+}
+
+// RefreshTokens returns a copy of every stored refresh token.
+func (m *TestDBRepo) RefreshTokens() []models.RefreshToken {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []models.RefreshToken
+	for _, t := range m.refreshTokens {
+		tokens = append(tokens, t)
+	}
+
+	return tokens
+}
+
+func (m *TestDBRepo) AllMovies(genre ...int) ([]*models.Movie, error) {
+	movies, _, err := m.ListMovies(models.MovieFilter{GenreIDs: genre})
+	return movies, err
+}
+
+func (m *TestDBRepo) ListMovies(filter models.MovieFilter) ([]*models.Movie, models.Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var movies []*models.Movie
+
+	for id, movie := range m.movies {
+		if len(filter.Ratings) > 0 && !containsString(filter.Ratings, movie.MPAARating) {
+			continue
+		}
+		if filter.YearFrom != 0 && movie.ReleaseDate.Year() < filter.YearFrom {
+			continue
+		}
+		if filter.YearTo != 0 && movie.ReleaseDate.Year() > filter.YearTo {
+			continue
+		}
+		if len(filter.GenreIDs) > 0 && !containsAnyInt(m.movieGenres[id], filter.GenreIDs) {
+			continue
+		}
+
+		movie := movie
+		movies = append(movies, &movie)
+	}
+
+	field, desc := filter.SortField(), filter.Descending()
+	sort.Slice(movies, func(i, j int) bool {
+		c := compareMovies(movies[i], movies[j], field)
+		if c == 0 {
+			return movies[i].ID < movies[j].ID
+		}
+		return (c < 0) != desc
+	})
+
+	metadata := models.NewMetadata(len(movies), filter)
+
+	if filter.PageSize > 0 {
+		offset := filter.Offset()
+		if offset > len(movies) {
+			offset = len(movies)
+		}
+		end := offset + filter.PageSize
+		if end > len(movies) {
+			end = len(movies)
+		}
+		movies = movies[offset:end]
+	}
+
+	return movies, metadata, nil
+}
+
+func (m *TestDBRepo) GetUserByEmail(email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *TestDBRepo) GetUserByID(id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &user, nil
+}
+
+func (m *TestDBRepo) GetUserRoles(userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := append([]string{}, m.roles[userID]...)
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+func (m *TestDBRepo) InsertUser(user models.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.emailTaken(user.Email, 0) {
+		return 0, &pgconn.PgError{Code: uniqueViolation, ConstraintName: "users_email_key"}
+	}
+
+	user.ID = m.nextUserID
+	m.nextUserID++
+
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (m *TestDBRepo) UpdateUser(user models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.users[user.ID]
+	if !ok {
+		return nil
+	}
+
+	if m.emailTaken(user.Email, user.ID) {
+		return &pgconn.PgError{Code: uniqueViolation, ConstraintName: "users_email_key"}
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	m.users[user.ID] = user
+
+	return nil
+}
+
+// emailTaken reports whether a user other than exceptID has email.
+func (m *TestDBRepo) emailTaken(email string, exceptID int) bool {
+	for id, u := range m.users {
+		if id != exceptID && u.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *TestDBRepo) ListUsers() ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var users []*models.User
+	for _, user := range m.users {
+		user := user
+		users = append(users, &user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return users, nil
+}
+
+func (m *TestDBRepo) InsertRefreshToken(token models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[token.UserID]; !ok {
+		return &pgconn.PgError{Code: foreignKeyViolation, ConstraintName: "refresh_tokens_user_id_fkey"}
+	}
+	if _, ok := m.refreshTokens[token.ID]; ok {
+		return &pgconn.PgError{Code: uniqueViolation, ConstraintName: "refresh_tokens_pkey"}
+	}
+
+	token.UsedAt = nil
+	token.RevokedAt = nil
+	token.CreatedAt = time.Now()
+	m.refreshTokens[token.ID] = token
+
+	return nil
+}
+
+func (m *TestDBRepo) GetRefreshToken(id string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.refreshTokens[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &token, nil
+}
+
+func (m *TestDBRepo) MarkRefreshTokenUsed(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.refreshTokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	m.refreshTokens[id] = token
+
+	return true, nil
+}
+
+func (m *TestDBRepo) RevokeRefreshTokenFamily(familyID string) error {
+	m.revokeRefreshTokens(func(t models.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+	return nil
+}
+
+func (m *TestDBRepo) RevokeUserRefreshTokens(userID int) error {
+	m.revokeRefreshTokens(func(t models.RefreshToken) bool {
+		return t.UserID == userID
+	})
+	return nil
+}
+
+func (m *TestDBRepo) revokeRefreshTokens(match func(models.RefreshToken) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range m.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			m.refreshTokens[id] = token
+		}
+	}
+}
+
+func (m *TestDBRepo) OneMovie(id int) (*models.Movie, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	movie.Genres = m.genresOf(id)
+
+	return &movie, nil
+}
+
+func (m *TestDBRepo) OneMovieForEdit(id int) (*models.Movie, []*models.Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[id]
+	if !ok {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	movie.Genres = m.genresOf(id)
+	for _, g := range movie.Genres {
+		movie.GenresArray = append(movie.GenresArray, g.ID)
+	}
+
+	var allGenres []*models.Genre
+	for _, g := range m.sortedGenres() {
+		allGenres = append(allGenres, &models.Genre{ID: g.ID, Genre: g.Genre})
+	}
+
+	return &movie, allGenres, nil
+}
+
+func (m *TestDBRepo) AllGenres() ([]*models.Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedGenres(), nil
+}
+
+func (m *TestDBRepo) GenresForMovies(movieIDs []int) (map[int][]*models.Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.genresForMoviesCalls++
+
+	genres := make(map[int][]*models.Genre, len(movieIDs))
+	for _, id := range movieIDs {
+		if g := m.genresOf(id); g != nil {
+			genres[id] = g
+		}
+	}
+
+	return genres, nil
+}
+
+func (m *TestDBRepo) OneGenre(id int) (*models.Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.genres[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &g, nil
+}
+
+func (m *TestDBRepo) InsertGenre(genre models.Genre) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.genreTaken(genre.Genre, 0) {
+		return 0, &pgconn.PgError{Code: uniqueViolation, ConstraintName: "genres_genre_key"}
+	}
+
+	genre.ID = m.nextGenreID
+	m.nextGenreID++
+
+	m.genres[genre.ID] = genre
+
+	return genre.ID, nil
+}
+
+func (m *TestDBRepo) UpdateGenre(genre models.Genre) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.genres[genre.ID]
+	if !ok {
+		return nil
+	}
+	if m.genreTaken(genre.Genre, genre.ID) {
+		return &pgconn.PgError{Code: uniqueViolation, ConstraintName: "genres_genre_key"}
+	}
+
+	existing.Genre = genre.Genre
+	existing.UpdatedAt = genre.UpdatedAt
+	m.genres[genre.ID] = existing
+
+	return nil
+}
+
+// genreTaken mirrors the unique index on lower(genre).
+func (m *TestDBRepo) genreTaken(name string, exceptID int) bool {
+	for id, g := range m.genres {
+		if id != exceptID && strings.ToLower(g.Genre) == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *TestDBRepo) GenreMovieCount(id int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, genres := range m.movieGenres {
+		if containsInt(genres, id) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *TestDBRepo) ReassignGenre(from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[to]; !ok {
+		for _, genres := range m.movieGenres {
+			if containsInt(genres, from) {
+				return &pgconn.PgError{Code: foreignKeyViolation, ConstraintName: "movies_genres_genre_id_fkey"}
+			}
+		}
+	}
+
+	for movieID, genres := range m.movieGenres {
+		if !containsInt(genres, from) {
+			continue
+		}
+
+		var kept []int
+		for _, g := range genres {
+			if g != from {
+				kept = append(kept, g)
+			}
+		}
+		if !containsInt(kept, to) {
+			kept = append(kept, to)
+		}
+		m.movieGenres[movieID] = kept
+	}
+
+	return nil
+}
+
+// DeleteGenre deletes a genre and, like the cascading foreign key, its links
+// to movies.
+func (m *TestDBRepo) DeleteGenre(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.genres, id)
+
+	for movieID, genres := range m.movieGenres {
+		var kept []int
+		for _, g := range genres {
+			if g != id {
+				kept = append(kept, g)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(m.movieGenres, movieID)
+		} else {
+			m.movieGenres[movieID] = kept
+		}
+	}
+
+	return nil
+}
+
+// genresOf joins a movie's genre ids with the genres table, ordered by name.
+func (m *TestDBRepo) genresOf(movieID int) []*models.Genre {
+	var genres []*models.Genre
+
+	for _, id := range m.movieGenres[movieID] {
+		g := m.genres[id]
+		genres = append(genres, &models.Genre{ID: g.ID, Genre: g.Genre})
+	}
+
+	sort.Slice(genres, func(i, j int) bool {
+		return genres[i].Genre < genres[j].Genre
+	})
+
+	return genres
+}
+
+func (m *TestDBRepo) sortedGenres() []*models.Genre {
+	var genres []*models.Genre
+	for _, g := range m.genres {
+		g := g
+		genres = append(genres, &g)
+	}
+
+	sort.Slice(genres, func(i, j int) bool {
+		return genres[i].Genre < genres[j].Genre
+	})
+
+	return genres
+}
+
+func (m *TestDBRepo) InsertMovie(movie models.Movie) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie.ID = m.nextMovieID
+	m.nextMovieID++
+
+	movie.Genres = nil
+	movie.GenresArray = nil
+	m.movies[movie.ID] = movie
+
+	return movie.ID, nil
+}
+
+func (m *TestDBRepo) UpdateMovieGenres(id int, genreIds []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, g := range genreIds {
+		if _, ok := m.movies[id]; !ok {
+			return &pgconn.PgError{Code: foreignKeyViolation, ConstraintName: "movies_genres_movie_id_fkey"}
+		}
+		if _, ok := m.genres[g]; !ok {
+			return &pgconn.PgError{Code: foreignKeyViolation, ConstraintName: "movies_genres_genre_id_fkey"}
+		}
+	}
+
+	m.movieGenres[id] = append([]int(nil), genreIds...)
+	if len(genreIds) == 0 {
+		delete(m.movieGenres, id)
+	}
+
+	return nil
+}
+
+func (m *TestDBRepo) UpdateMovie(movie models.Movie) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.movies[movie.ID]
+	if !ok {
+		return nil
+	}
+
+	existing.Title = movie.Title
+	existing.Description = movie.Description
+	existing.ReleaseDate = movie.ReleaseDate
+	existing.RunTime = movie.RunTime
+	existing.MPAARating = movie.MPAARating
+	existing.UpdatedAt = movie.UpdatedAt
+	existing.Image = movie.Image
+	m.movies[movie.ID] = existing
+
+	return nil
+}
+
+func (m *TestDBRepo) UpdateMovieImage(id int, image string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[id]
+	if !ok {
+		return nil
+	}
+
+	movie.Image = image
+	m.movies[id] = movie
+
+	return nil
+}
+
+func (m *TestDBRepo) DeleteMovie(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.movies, id)
+	delete(m.movieGenres, id)
+
+	return nil
+}
+
+// compareMovies orders two movies by a MovieFilter sort field the way the
+// postgres query does.
+func compareMovies(a, b *models.Movie, field string) int {
+	switch field {
+	case "release_date":
+		return a.ReleaseDate.Compare(b.ReleaseDate)
+	case "runtime":
+		return a.RunTime - b.RunTime
+	case "mpaa_rating":
+		return ratingRank(a.MPAARating) - ratingRank(b.MPAARating)
+	default:
+		return strings.Compare(a.Title, b.Title)
+	}
+}
+
+// ratingRank mirrors array_position, which puts unknown ratings last.
+func ratingRank(rating string) int {
+	for i, r := range models.MPAARatings {
+		if r == rating {
+			return i
+		}
+	}
+	return len(models.MPAARatings)
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyInt(values, wanted []int) bool {
+	for _, v := range wanted {
+		if containsInt(values, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, n := range values {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
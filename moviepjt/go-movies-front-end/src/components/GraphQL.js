@@ -2,36 +2,81 @@ import { useEffect, useState } from "react";
 import { Link } from "react-router-dom";
 import Input from './form/Input';
 
-const GraphQL = () => {
-    // set up stateful variables
-    const [movies, setMovies] = useState([]);
-    const [searchTerm, setSearchTerm] = useState("");
-    const [fullList, setFullList] = useState([]);
+const listQuery = `
+    query List {
+        list {
+            id
+            title
+            runtime
+            release_date
+            mpaa_rating
+        }
+    }`;
+
+const searchQuery = `
+    query Search($titleContains: String) {
+        search(titleContains: $titleContains) {
+            id
+            title
+            runtime
+            release_date
+            mpaa_rating
+        }
+    }`;
+
+// sha256 returns the hex encoded SHA-256 hash of text.
+const sha256 = async (text) => {
+    const digest = await crypto.subtle.digest("SHA-256", new TextEncoder().encode(text));
+    return Array.from(new Uint8Array(digest))
+        .map((b) => b.toString(16).padStart(2, "0"))
+        .join("");
+}
 
-    // perform a search
-    const performSearch = () => {
-        const payload = `
-        {
-            search(titleContains: "${searchTerm}") {
-                id
-                title
-                runtime
-                release_date
-                mpaa_rating
-            }
-        }`;
+// runQuery first sends only the hash of the query, so the request is a small,
+// cacheable GET. If the server doesn't know the hash yet it sends the full query
+// once, and the server remembers it for next time.
+const runQuery = async (query, variables = {}) => {
+    const extensions = {
+        persistedQuery: { version: 1, sha256Hash: await sha256(query) },
+    };
+
+    const params = new URLSearchParams({
+        variables: JSON.stringify(variables),
+        extensions: JSON.stringify(extensions),
+    });
+
+    let response = await fetch(`${process.env.REACT_APP_BACKEND}/graph?${params}`)
+        .then((response) => response.json());
+
+    const notFound = (response.errors || [])
+        .some((e) => e.extensions && e.extensions.code === "PERSISTED_QUERY_NOT_FOUND");
 
+    if (notFound) {
         const headers = new Headers();
-        headers.append("Content-Type", "application/graphql");
+        headers.append("Content-Type", "application/json");
 
         const requestOptions = {
             method: "POST",
-            body: payload,
             headers: headers,
+            body: JSON.stringify({ query, variables, extensions }),
         }
 
-        fetch(`${process.env.REACT_APP_BACKEND}/graph`, requestOptions)
-            .then((response) => response.json())
+        response = await fetch(`${process.env.REACT_APP_BACKEND}/graph`, requestOptions)
+            .then((response) => response.json());
+    }
+
+    return response;
+}
+
+const GraphQL = () => {
+    // set up stateful variables
+    const [movies, setMovies] = useState([]);
+    const [searchTerm, setSearchTerm] = useState("");
+    const [fullList, setFullList] = useState([]);
+
+    // perform a search
+    const performSearch = () => {
+        runQuery(searchQuery, { titleContains: searchTerm })
             .then((response) => {
                 let theList = Object.values(response.data.search);
                 setMovies(theList);
@@ -54,28 +99,7 @@ const GraphQL = () => {
 
     // useEffect
     useEffect(() => {
-        const payload = `
-        {
-            list {
-                id
-                title
-                runtime
-                release_date
-                mpaa_rating
-            }
-        }`;
-
-        const headers = new Headers();
-        headers.append("Content-Type", "application/grapql");
-
-        const requestOptions = {
-            method: "POST",
-            headers: headers,
-            body: payload,
-        }
-
-        fetch(`${process.env.REACT_APP_BACKEND}/graph`, requestOptions)
-            .then((response) => response.json())
+        runQuery(listQuery)
             .then((response) => {
                 let theList = Object.values(response.data.list);
                 setMovies(theList);
@@ -3,6 +3,8 @@ import { Link, useNavigate, useOutletContext } from "react-router-dom";
 
 const ManageCatalogue = () => {
     const [movies, setMovies] = useState([]);
+    const [metadata, setMetadata] = useState({});
+    const [page, setPage] = useState(1);
     const {jwtToken} = useOutletContext();
     const navigate = useNavigate();
 
@@ -21,16 +23,17 @@ const ManageCatalogue = () => {
             headers: headers,
         }
 
-        fetch(`${process.env.REACT_APP_BACKEND}/admin/movies`, requestOptions)
+        fetch(`${process.env.REACT_APP_BACKEND}/admin/movies?page=${page}`, requestOptions)
             .then((response) => response.json())
             .then((data) => {
-                setMovies(data);
+                setMovies(data.movies ?? []);
+                setMetadata(data.metadata ?? {});
             })
             .catch(err => {
                 console.log(err);
             })
 
-    }, [jwtToken, navigate]);
+    }, [jwtToken, navigate, page]);
 
     return(
         <div>
@@ -58,6 +61,15 @@ const ManageCatalogue = () => {
                     ))}
                 </tbody>
             </table>
+            {metadata.last_page > 1 &&
+                <nav className="d-flex justify-content-between align-items-center">
+                    <button className="btn btn-outline-secondary" disabled={page <= 1}
+                        onClick={() => setPage(page - 1)}>Previous</button>
+                    <span>Page {metadata.current_page} of {metadata.last_page}</span>
+                    <button className="btn btn-outline-secondary" disabled={page >= metadata.last_page}
+                        onClick={() => setPage(page + 1)}>Next</button>
+                </nav>
+            }
         </div>
     )
 }
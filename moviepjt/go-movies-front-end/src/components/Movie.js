@@ -1,6 +1,15 @@
 import { useEffect, useState } from "react";
 import { useParams } from "react-router-dom";
 
+// posterSrc returns the url of a movie's poster. New posters are served by the
+// backend; older movies still hold a TMDB poster path.
+const posterSrc = (image) => {
+    if (image.startsWith("/images/")) {
+        return `${process.env.REACT_APP_BACKEND}${image}`;
+    }
+    return `https://image.tmdb.org/t/p/w200/${image}`;
+}
+
 const Movie = () => {
     const [movie, setMovie] = useState({});
     let { id } = useParams();
@@ -41,7 +50,7 @@ const Movie = () => {
 
             {movie.image !== "" &&
                 <div className="mb-3">
-                    <img src={`https://image.tmdb.org/t/p/w200/${movie.image}`} alt="poster" />
+                    <img src={posterSrc(movie.image)} alt="poster" />
                 </div>
             }
 
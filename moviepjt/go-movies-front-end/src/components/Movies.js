@@ -3,6 +3,8 @@ import { Link } from "react-router-dom";
 
 const Movies = () => {
     const [movies, setMovies] = useState([]);
+    const [metadata, setMetadata] = useState({});
+    const [page, setPage] = useState(1);
 
     useEffect( () => {
         const headers = new Headers();
@@ -13,16 +15,17 @@ const Movies = () => {
             headers: headers,
         }
 
-        fetch(`${process.env.REACT_APP_BACKEND}/movies`, requestOptions)
+        fetch(`${process.env.REACT_APP_BACKEND}/movies?page=${page}`, requestOptions)
             .then((response) => response.json())
             .then((data) => {
-                setMovies(data);
+                setMovies(data.movies ?? []);
+                setMetadata(data.metadata ?? {});
             })
             .catch(err => {
                 console.log(err);
             })
 
-    }, []);
+    }, [page]);
 
     return(
         <div>
@@ -50,6 +53,15 @@ const Movies = () => {
                     ))}
                 </tbody>
             </table>
+            {metadata.last_page > 1 &&
+                <nav className="d-flex justify-content-between align-items-center">
+                    <button className="btn btn-outline-secondary" disabled={page <= 1}
+                        onClick={() => setPage(page - 1)}>Previous</button>
+                    <span>Page {metadata.current_page} of {metadata.last_page}</span>
+                    <button className="btn btn-outline-secondary" disabled={page >= metadata.last_page}
+                        onClick={() => setPage(page + 1)}>Next</button>
+                </nav>
+            }
         </div>
     )
 }